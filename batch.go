@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hamba/cache/v2/internal/decoder"
+)
+
+// batchConcurrency is the maximum number of keys executed
+// concurrently by a batch fallback.
+const batchConcurrency = 10
+
+// BatchOpType is the type of a batch operation.
+type BatchOpType int
+
+// Batch operation types.
+const (
+	BatchGet BatchOpType = iota
+	BatchSet
+	BatchAdd
+	BatchReplace
+	BatchDelete
+	BatchInc
+	BatchDec
+)
+
+// BatchOp is a queued batch operation.
+type BatchOp struct {
+	Type   BatchOpType
+	Key    string
+	Value  interface{}
+	Expire time.Duration
+	Delta  uint64
+}
+
+// BatchCache represents a cache instance that can execute batches
+// of operations together.
+type BatchCache interface {
+	Cache
+
+	// ExecBatch executes the operations, returning an item for each
+	// operation in order.
+	ExecBatch(ctx context.Context, ops []BatchOp) []Item
+}
+
+// Batch queues mixed operations to be executed together.
+//
+// The zero value is an empty batch ready to use.
+type Batch struct {
+	ops []BatchOp
+}
+
+// NewBatch returns an empty batch.
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Get queues a get of the given key, returning the index of its result.
+func (b *Batch) Get(k string) int {
+	return b.queue(BatchOp{Type: BatchGet, Key: k})
+}
+
+// Set queues a set of the given key, returning the index of its result.
+func (b *Batch) Set(k string, v interface{}, expire time.Duration) int {
+	return b.queue(BatchOp{Type: BatchSet, Key: k, Value: v, Expire: expire})
+}
+
+// Add queues an add of the given key, returning the index of its result.
+func (b *Batch) Add(k string, v interface{}, expire time.Duration) int {
+	return b.queue(BatchOp{Type: BatchAdd, Key: k, Value: v, Expire: expire})
+}
+
+// Replace queues a replace of the given key, returning the index of its result.
+func (b *Batch) Replace(k string, v interface{}, expire time.Duration) int {
+	return b.queue(BatchOp{Type: BatchReplace, Key: k, Value: v, Expire: expire})
+}
+
+// Delete queues a delete of the given key, returning the index of its result.
+func (b *Batch) Delete(k string) int {
+	return b.queue(BatchOp{Type: BatchDelete, Key: k})
+}
+
+// Inc queues an increment of the given key, returning the index of its result.
+func (b *Batch) Inc(k string, v uint64) int {
+	return b.queue(BatchOp{Type: BatchInc, Key: k, Delta: v})
+}
+
+// Dec queues a decrement of the given key, returning the index of its result.
+func (b *Batch) Dec(k string, v uint64) int {
+	return b.queue(BatchOp{Type: BatchDec, Key: k, Delta: v})
+}
+
+func (b *Batch) queue(op BatchOp) int {
+	b.ops = append(b.ops, op)
+	return len(b.ops) - 1
+}
+
+// Ops returns the queued operations.
+func (b *Batch) Ops() []BatchOp {
+	return b.ops
+}
+
+// Len returns the number of queued operations.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Exec executes the queued operations against the cache, returning an item
+// for each operation in order. The items of write operations only hold an
+// error, and increments and decrements hold the new value.
+//
+// If the cache is a BatchCache, the operations are executed together.
+// Otherwise the operations on each key are executed in order, with different
+// keys executed concurrently.
+func (b *Batch) Exec(ctx context.Context, c Cache) []Item {
+	if bc, ok := c.(BatchCache); ok {
+		return bc.ExecBatch(ctx, b.ops)
+	}
+
+	byKey := map[string][]int{}
+	keys := []string{}
+	for i, op := range b.ops {
+		if _, ok := byKey[op.Key]; !ok {
+			keys = append(keys, op.Key)
+		}
+		byKey[op.Key] = append(byKey[op.Key], i)
+	}
+
+	items := make([]Item, len(b.ops))
+	sem := make(chan struct{}, batchConcurrency)
+
+	var wg sync.WaitGroup
+	for _, k := range keys {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(idxs []int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			for _, i := range idxs {
+				items[i] = execOp(ctx, c, b.ops[i])
+			}
+		}(byKey[k])
+	}
+	wg.Wait()
+
+	return items
+}
+
+func execOp(ctx context.Context, c Cache, op BatchOp) Item {
+	dec := decoder.StringDecoder{}
+
+	var err error
+	switch op.Type {
+	case BatchGet:
+		return c.Get(ctx, op.Key)
+	case BatchSet:
+		err = c.Set(ctx, op.Key, op.Value, op.Expire)
+	case BatchAdd:
+		err = c.Add(ctx, op.Key, op.Value, op.Expire)
+	case BatchReplace:
+		err = c.Replace(ctx, op.Key, op.Value, op.Expire)
+	case BatchDelete:
+		err = c.Delete(ctx, op.Key)
+	case BatchInc, BatchDec:
+		var n int64
+		if op.Type == BatchInc {
+			n, err = c.Inc(ctx, op.Key, op.Delta)
+		} else {
+			n, err = c.Dec(ctx, op.Key, op.Delta)
+		}
+		if err != nil {
+			return NewItem(dec, []byte(nil), err)
+		}
+		return NewItem(dec, []byte(strconv.FormatInt(n, 10)), nil)
+	default:
+		err = errors.New("cache: unknown batch operation")
+	}
+
+	return NewItem(dec, []byte(nil), err)
+}
@@ -0,0 +1,69 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch_Exec(t *testing.T) {
+	c := memory.New()
+	_ = c.Set(context.Background(), "test", "foo", 0)
+
+	b := cache.NewBatch()
+	inc := b.Inc("counter", 2)
+	set := b.Set("marker", "bar", time.Minute)
+	add := b.Add("test", "baz", 0)
+	get := b.Get("marker")
+	miss := b.Get("_")
+	dec := b.Dec("counter", 1)
+	del := b.Delete("test")
+	rep := b.Replace("test", "baz", 0)
+
+	items := b.Exec(context.Background(), c)
+
+	require.Len(t, items, b.Len())
+	i, err := items[inc].Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), i)
+	assert.NoError(t, items[set].Err)
+	assert.ErrorIs(t, items[add].Err, cache.ErrNotStored)
+	str, err := items[get].String()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", str)
+	assert.ErrorIs(t, items[miss].Err, cache.ErrCacheMiss)
+	i, err = items[dec].Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), i)
+	assert.NoError(t, items[del].Err)
+	assert.ErrorIs(t, items[rep].Err, cache.ErrNotStored)
+}
+
+func TestBatch_ExecUsesBatchCache(t *testing.T) {
+	c := &batchCache{Memory: memory.New()}
+
+	var b cache.Batch
+	b.Get("test")
+	b.Delete("test")
+
+	items := b.Exec(context.Background(), c)
+
+	assert.Len(t, items, 2)
+	assert.Equal(t, b.Ops(), c.ops)
+}
+
+type batchCache struct {
+	*memory.Memory
+
+	ops []cache.BatchOp
+}
+
+func (c *batchCache) ExecBatch(_ context.Context, ops []cache.BatchOp) []cache.Item {
+	c.ops = ops
+	return make([]cache.Item, len(ops))
+}
@@ -14,6 +14,13 @@ var (
 	// the condition was not met.
 	ErrNotStored = errors.New("cache: not stored")
 
+	// ErrCASConflict is returned if a CompareAndSwap failed because the item
+	// was modified since it was read.
+	ErrCASConflict = errors.New("cache: cas conflict")
+
+	// ErrNegativeInitial is returned if a counter initial value is negative.
+	ErrNegativeInitial = errors.New("cache: negative counter initial value")
+
 	// Null is the null Cache instance.
 	Null = &nullCache{}
 )
@@ -45,6 +52,60 @@ type Cache interface {
 	Dec(ctx context.Context, k string, v uint64) (int64, error)
 }
 
+// NoExpiration is the TTL of an item that does not expire.
+const NoExpiration time.Duration = -1
+
+// CASToken is an opaque compare and swap token. It is only valid for
+// the cache instance that returned it.
+type CASToken interface{}
+
+// CASItem is an item with its compare and swap token.
+type CASItem struct {
+	Item
+
+	// Token is the compare and swap token of the item, or nil
+	// if the item could not be read.
+	Token CASToken
+}
+
+// CASCache represents a cache instance supporting compare and swap.
+type CASCache interface {
+	Cache
+
+	// Gets gets the item for the given key with its compare and swap token.
+	Gets(ctx context.Context, k string) CASItem
+
+	// CompareAndSwap sets the item in the cache, but only if it has not been
+	// modified since the token was read. ErrCASConflict is returned if the item
+	// was modified, and ErrCacheMiss if it no longer exists.
+	CompareAndSwap(ctx context.Context, k string, v interface{}, token CASToken, expire time.Duration) error
+}
+
+// TouchCache represents a cache instance supporting sliding expirations.
+//
+// An expiration of zero removes the expiration of the item.
+type TouchCache interface {
+	Cache
+
+	// Touch updates the expiration of the item with the given key.
+	// ErrCacheMiss is returned if the item does not exist.
+	Touch(ctx context.Context, k string, expire time.Duration) error
+
+	// GetAndTouch gets the item for the given key and updates its expiration.
+	GetAndTouch(ctx context.Context, k string, expire time.Duration) Item
+}
+
+// TTLCache represents a cache instance that can report the remaining
+// lifetime of its items.
+type TTLCache interface {
+	Cache
+
+	// TTL returns the remaining lifetime of the item with the given key, or
+	// NoExpiration if it does not expire. ErrCacheMiss is returned if the
+	// item does not exist.
+	TTL(ctx context.Context, k string) (time.Duration, error)
+}
+
 type nullDecoder struct{}
 
 func (d nullDecoder) Bool(v interface{}) (bool, error) {
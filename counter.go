@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Underflow is the behaviour of a counter decremented below zero.
+type Underflow int
+
+// Underflow modes.
+const (
+	// UnderflowClamp clamps the counter at zero.
+	UnderflowClamp Underflow = iota
+
+	// UnderflowReject leaves the counter unchanged, returning
+	// ErrNotStored with the current value. A missing counter is
+	// still created from its initial value.
+	UnderflowReject
+)
+
+// CounterOptions configures a counter operation.
+type CounterOptions struct {
+	// Initial is the value a missing counter starts from, before
+	// the delta is applied. It must not be negative.
+	Initial int64
+
+	// Expire is the expiration set when the counter is created.
+	// The expiration of an existing counter is not changed.
+	Expire time.Duration
+
+	// Underflow is the behaviour when the counter would be
+	// decremented below zero.
+	Underflow Underflow
+}
+
+// CounterCache represents a cache instance supporting counters with
+// consistent initialisation, expiration and underflow behaviour.
+type CounterCache interface {
+	Cache
+
+	// IncWithOptions adds the delta to the counter with the given key,
+	// returning the new value. A negative delta decrements the counter.
+	IncWithOptions(ctx context.Context, k string, delta int64, opts CounterOptions) (int64, error)
+}
+
+// FloatCache represents a cache instance supporting floating-point increments.
+type FloatCache interface {
+	Cache
+
+	// IncFloat adds the delta to the key, returning the new value. A missing
+	// key is created at zero before the delta is applied.
+	IncFloat(ctx context.Context, k string, delta float64) (float64, error)
+}
@@ -0,0 +1,33 @@
+package cache_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/hamba/cache/v2"
+)
+
+func ExampleBatch() {
+	var c cache.Cache // Any cache
+
+	b := cache.NewBatch()
+	visits := b.Inc("visits", 1)
+	b.Set("last-visit", time.Now().Unix(), time.Hour)
+	user := b.Get("user:1")
+
+	items := b.Exec(context.Background(), c)
+	if items[visits].Err != nil {
+		// Handle error
+	}
+
+	_, _ = items[visits].Int64()
+	_, _ = items[user].String()
+}
+
+func ExampleReady() {
+	var primary, secondary cache.Cache // Create your caches
+
+	if err := cache.Ready(context.Background(), primary, secondary); err != nil {
+		// Handle not ready
+	}
+}
@@ -0,0 +1,29 @@
+package failover_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/failover"
+	"github.com/hamba/cache/v2/memcache"
+	"github.com/hamba/cache/v2/redis"
+)
+
+func ExampleNew() {
+	primary, err := redis.New("redis://localhost:6379")
+	if err != nil {
+		// Handle error
+	}
+	fallback := memcache.New("localhost:11211")
+
+	c := failover.New(primary, []cache.Cache{fallback}, failover.WithInterval(time.Second))
+	defer c.Close()
+
+	i := c.Get(context.Background(), "foobar")
+	if i.Err != nil {
+		// Handle error
+	}
+
+	_, _ = i.Float64()
+}
@@ -0,0 +1,368 @@
+// Package failover implements a cache that falls back to secondary caches when the primary fails.
+//
+// All operations are sent to the first healthy cache in the list, starting with
+// the primary. When an operation fails with a backend error, the cache is marked
+// unhealthy and the operation is retried against the next healthy cache. Unhealthy
+// caches are checked in the background and automatically switched back to once
+// they recover.
+//
+// Writes are only sent to the active cache and are not replayed on recovery. To
+// avoid serving stale data after a switch, written keys are deleted from all
+// other healthy caches, and keys written while a cache was unhealthy are deleted
+// from it before it is used again. If more keys are written than are tracked,
+// the cache is flushed before it is used again, or stays unhealthy if it cannot
+// be flushed.
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hamba/cache/v2"
+)
+
+// ErrNoHealthyCache is returned if all caches are unhealthy.
+var ErrNoHealthyCache = errors.New("failover: no healthy cache")
+
+// HealthCheckFunc checks the health of a cache.
+type HealthCheckFunc func(ctx context.Context, c cache.Cache) error
+
+// OptsFunc represents an configuration function for Failover.
+type OptsFunc func(*Failover)
+
+// WithHealthCheck configures the function used to check if an unhealthy cache has recovered.
+func WithHealthCheck(fn HealthCheckFunc) OptsFunc {
+	return func(f *Failover) {
+		f.check = fn
+	}
+}
+
+// WithInterval configures the interval between health checks.
+func WithInterval(d time.Duration) OptsFunc {
+	return func(f *Failover) {
+		f.interval = d
+	}
+}
+
+// WithTimeout configures the timeout of a single health check.
+func WithTimeout(d time.Duration) OptsFunc {
+	return func(f *Failover) {
+		f.timeout = d
+	}
+}
+
+// WithFailureFunc configures the function used to determine if an error
+// indicates a cache failure.
+func WithFailureFunc(fn func(error) bool) OptsFunc {
+	return func(f *Failover) {
+		f.isFailure = fn
+	}
+}
+
+// WithMaxDirtyKeys configures the maximum number of keys written during an outage
+// that are tracked per cache to be deleted on recovery.
+//
+// When more keys are written, the cache is flushed on recovery if it is a
+// cache.Flusher. Otherwise it stays unhealthy, as it may hold stale values
+// for any key.
+func WithMaxDirtyKeys(n int) OptsFunc {
+	return func(f *Failover) {
+		f.maxDirty = n
+	}
+}
+
+type backend struct {
+	cache   cache.Cache
+	healthy int32
+
+	// mu is held exclusively while the cache recovers, so a key
+	// cannot be written while the cache switches back to healthy.
+	mu sync.RWMutex
+
+	dirtyMu  sync.Mutex
+	dirty    map[string]struct{}
+	overflow bool
+}
+
+func (b *backend) isHealthy() bool {
+	return atomic.LoadInt32(&b.healthy) == 1
+}
+
+func (b *backend) setHealthy(v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+	atomic.StoreInt32(&b.healthy, i)
+}
+
+// Failover is a cache that falls back to secondary caches on failure.
+type Failover struct {
+	backends []*backend
+
+	check     HealthCheckFunc
+	interval  time.Duration
+	timeout   time.Duration
+	isFailure func(error) bool
+	maxDirty  int
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// New returns a failover cache using the primary cache, falling back to
+// the given caches in order.
+func New(primary cache.Cache, fallbacks []cache.Cache, opts ...OptsFunc) *Failover {
+	f := &Failover{
+		check:     defaultHealthCheck,
+		interval:  5 * time.Second,
+		timeout:   time.Second,
+		isFailure: IsFailure,
+		maxDirty:  10000,
+		done:      make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	caches := append([]cache.Cache{primary}, fallbacks...)
+	f.backends = make([]*backend, 0, len(caches))
+	for _, c := range caches {
+		f.backends = append(f.backends, &backend{
+			cache:   c,
+			healthy: 1,
+			dirty:   map[string]struct{}{},
+		})
+	}
+
+	f.wg.Add(1)
+	go f.run()
+
+	return f
+}
+
+// Get gets the item for the given key.
+func (f *Failover) Get(ctx context.Context, key string) cache.Item {
+	var item cache.Item
+	err := f.do(ctx, "", func(c cache.Cache) error {
+		item = c.Get(ctx, key)
+		return item.Err
+	})
+	if item.Err == nil && err != nil {
+		return cache.NewItem(nil, nil, err)
+	}
+	return item
+}
+
+// GetMulti gets the items for the given keys.
+func (f *Failover) GetMulti(ctx context.Context, keys ...string) ([]cache.Item, error) {
+	var items []cache.Item
+	err := f.do(ctx, "", func(c cache.Cache) error {
+		var err error
+		items, err = c.GetMulti(ctx, keys...)
+		return err
+	})
+	return items, err
+}
+
+// Set sets the item in the cache.
+func (f *Failover) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	return f.do(ctx, key, func(c cache.Cache) error {
+		return c.Set(ctx, key, value, expire)
+	})
+}
+
+// Add sets the item in the cache, but only if the key does not already exist.
+func (f *Failover) Add(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	return f.do(ctx, key, func(c cache.Cache) error {
+		return c.Add(ctx, key, value, expire)
+	})
+}
+
+// Replace sets the item in the cache, but only if the key already exists.
+func (f *Failover) Replace(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	return f.do(ctx, key, func(c cache.Cache) error {
+		return c.Replace(ctx, key, value, expire)
+	})
+}
+
+// Delete deletes the item with the given key.
+//
+// The delete is mirrored to all healthy caches.
+func (f *Failover) Delete(ctx context.Context, key string) error {
+	return f.do(ctx, key, func(c cache.Cache) error {
+		return c.Delete(ctx, key)
+	})
+}
+
+// Inc increments a key by the value.
+func (f *Failover) Inc(ctx context.Context, key string, value uint64) (int64, error) {
+	var v int64
+	err := f.do(ctx, key, func(c cache.Cache) error {
+		var err error
+		v, err = c.Inc(ctx, key, value)
+		return err
+	})
+	return v, err
+}
+
+// Dec decrements a key by the value.
+func (f *Failover) Dec(ctx context.Context, key string, value uint64) (int64, error) {
+	var v int64
+	err := f.do(ctx, key, func(c cache.Cache) error {
+		var err error
+		v, err = c.Dec(ctx, key, value)
+		return err
+	})
+	return v, err
+}
+
+// Close stops the background health checks.
+func (f *Failover) Close() error {
+	f.closeOnce.Do(func() { close(f.done) })
+	f.wg.Wait()
+	return nil
+}
+
+// do runs fn against the first healthy cache, failing over to the next
+// healthy cache on failure. If key is not empty, the key is invalidated
+// on all other caches.
+func (f *Failover) do(ctx context.Context, key string, fn func(c cache.Cache) error) error {
+	var active *backend
+	err := ErrNoHealthyCache
+	for _, b := range f.backends {
+		if !b.isHealthy() {
+			continue
+		}
+
+		active = b
+		err = fn(b.cache)
+		if err == nil || ctx.Err() != nil || !f.isFailure(err) {
+			break
+		}
+
+		b.setHealthy(false)
+	}
+
+	if key != "" {
+		f.invalidate(ctx, key, active)
+	}
+	return err
+}
+
+// invalidate removes the key from all caches other than the active cache,
+// so they do not serve a stale value once they become active. The key is
+// deleted from healthy caches and marked as dirty on unhealthy caches.
+func (f *Failover) invalidate(ctx context.Context, key string, active *backend) {
+	for _, b := range f.backends {
+		if b == active && b.isHealthy() {
+			continue
+		}
+		f.invalidateBackend(ctx, b, key)
+	}
+}
+
+func (f *Failover) invalidateBackend(ctx context.Context, b *backend, key string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.isHealthy() {
+		err := b.cache.Delete(ctx, key)
+		if err == nil || !f.isFailure(err) {
+			return
+		}
+		b.setHealthy(false)
+	}
+
+	b.dirtyMu.Lock()
+	if _, ok := b.dirty[key]; ok || len(b.dirty) < f.maxDirty {
+		b.dirty[key] = struct{}{}
+	} else {
+		b.overflow = true
+	}
+	b.dirtyMu.Unlock()
+}
+
+func (f *Failover) run() {
+	defer f.wg.Done()
+
+	tick := time.NewTicker(f.interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-tick.C:
+		}
+
+		for _, b := range f.backends {
+			if b.isHealthy() {
+				continue
+			}
+			f.recover(b)
+		}
+	}
+}
+
+func (f *Failover) recover(b *backend) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+
+	if err := f.check(ctx, b.cache); err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Writers are excluded, so the dirty keys can be drained without dirtyMu.
+	if b.overflow {
+		fl, ok := b.cache.(cache.Flusher)
+		if !ok {
+			// Not all stale keys are known, so the cache cannot be used again.
+			return
+		}
+		if err := fl.Flush(ctx); err != nil {
+			return
+		}
+		b.dirty = map[string]struct{}{}
+		b.overflow = false
+	}
+
+	for k := range b.dirty {
+		if err := b.cache.Delete(ctx, k); err != nil && f.isFailure(err) {
+			return
+		}
+		delete(b.dirty, k)
+	}
+
+	b.setHealthy(true)
+}
+
+// IsFailure determines if the given error indicates a cache failure. Cache
+// misses, conditional write failures and context errors are not failures.
+func IsFailure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, cache.ErrCacheMiss),
+		errors.Is(err, cache.ErrNotStored),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	return true
+}
+
+func defaultHealthCheck(ctx context.Context, c cache.Cache) error {
+	err := c.Get(ctx, "failover:health").Err
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,443 @@
+package failover_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/failover"
+	"github.com/hamba/cache/v2/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailover_Implements(t *testing.T) {
+	f := failover.New(newFailingCache(), nil)
+	t.Cleanup(func() { _ = f.Close() })
+
+	assert.Implements(t, (*cache.Cache)(nil), f)
+}
+
+func TestFailover_UsesPrimary(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(primary, []cache.Cache{fallback})
+	t.Cleanup(func() { _ = f.Close() })
+
+	err := f.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	str, err := f.Get(ctx, "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+	assert.True(t, primary.has("test"))
+	assert.False(t, fallback.has("test"))
+}
+
+func TestFailover_CacheMissDoesNotFailover(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(primary, []cache.Cache{fallback})
+	t.Cleanup(func() { _ = f.Close() })
+
+	_ = fallback.Set(ctx, "test", "foobar", 0)
+
+	err := f.Get(ctx, "test").Err
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	err = f.Add(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+	err = f.Add(ctx, "test", "foobar", 0)
+	assert.ErrorIs(t, err, cache.ErrNotStored)
+}
+
+func TestFailover_FallsBackOnFailure(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(primary, []cache.Cache{fallback})
+	t.Cleanup(func() { _ = f.Close() })
+
+	primary.setErr(errors.New("test error"))
+
+	err := f.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	str, err := f.Get(ctx, "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+	assert.True(t, fallback.has("test"))
+}
+
+func TestFailover_AllFailed(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(primary, []cache.Cache{fallback})
+	t.Cleanup(func() { _ = f.Close() })
+
+	primary.setErr(errors.New("test error"))
+	fallback.setErr(errors.New("test error"))
+
+	err := f.Set(ctx, "test", "foobar", 0)
+	assert.EqualError(t, err, "test error")
+
+	err = f.Get(ctx, "test").Err
+	assert.ErrorIs(t, err, failover.ErrNoHealthyCache)
+
+	_, err = f.GetMulti(ctx, "test")
+	assert.ErrorIs(t, err, failover.ErrNoHealthyCache)
+}
+
+func TestFailover_DeleteIsMirrored(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(primary, []cache.Cache{fallback})
+	t.Cleanup(func() { _ = f.Close() })
+
+	_ = primary.Set(ctx, "test", "foobar", 0)
+	_ = fallback.Set(ctx, "test", "foobar", 0)
+
+	err := f.Delete(ctx, "test")
+	require.NoError(t, err)
+
+	assert.False(t, primary.has("test"))
+	assert.False(t, fallback.has("test"))
+}
+
+func TestFailover_SwitchesBackOnRecovery(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(primary, []cache.Cache{fallback}, failover.WithInterval(10*time.Millisecond))
+	t.Cleanup(func() { _ = f.Close() })
+
+	_ = primary.Set(ctx, "test", "old", 0)
+	primary.setErr(errors.New("test error"))
+
+	_, err := f.Inc(ctx, "counter", 1)
+	require.NoError(t, err)
+	err = f.Set(ctx, "test", "new", 0)
+	require.NoError(t, err)
+
+	primary.setErr(nil)
+
+	assert.Eventually(t, func() bool {
+		return !primary.has("test")
+	}, time.Second, 10*time.Millisecond)
+
+	err = f.Set(ctx, "other", "foobar", 0)
+	require.NoError(t, err)
+	assert.True(t, primary.has("other"))
+	assert.False(t, fallback.has("other"))
+}
+
+func TestFailover_FallbackIsInvalidatedOnWrite(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(primary, []cache.Cache{fallback}, failover.WithInterval(10*time.Millisecond))
+	t.Cleanup(func() { _ = f.Close() })
+
+	primary.setErr(errors.New("test error"))
+	err := f.Set(ctx, "test", "v1", 0)
+	require.NoError(t, err)
+
+	primary.setErr(nil)
+	assert.Eventually(t, func() bool {
+		_ = f.Set(ctx, "test", "v2", 0)
+		return primary.has("test")
+	}, time.Second, 10*time.Millisecond)
+
+	primary.setErr(errors.New("test error"))
+	err = f.Get(ctx, "test").Err
+
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestFailover_WriteDuringRecoveryIsInvalidated(t *testing.T) {
+	ctx := context.Background()
+	primary := newFailingCache()
+	fallback := &blockingCache{failingCache: newFailingCache(), block: make(chan struct{})}
+	f := failover.New(primary, []cache.Cache{fallback}, failover.WithInterval(10*time.Millisecond))
+	t.Cleanup(func() { _ = f.Close() })
+
+	_ = primary.Set(ctx, "test", "old", 0)
+	primary.setErr(errors.New("test error"))
+	_ = f.Get(ctx, "test")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_ = f.Set(ctx, "test", "new", 0)
+	}()
+	<-fallback.block
+
+	primary.setErr(nil)
+	assert.Eventually(t, func() bool {
+		str, _ := f.Get(ctx, "test").String()
+		return str == "old"
+	}, time.Second, 10*time.Millisecond)
+
+	fallback.block <- struct{}{}
+	<-done
+
+	assert.False(t, primary.has("test"))
+}
+
+func TestFailover_FlushesOnDirtyKeyOverflow(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(primary, []cache.Cache{fallback},
+		failover.WithInterval(10*time.Millisecond),
+		failover.WithMaxDirtyKeys(1),
+	)
+	t.Cleanup(func() { _ = f.Close() })
+
+	_ = primary.Set(ctx, "test1", "old", 0)
+	_ = primary.Set(ctx, "test2", "old", 0)
+	primary.setErr(errors.New("test error"))
+
+	_ = f.Set(ctx, "test1", "new", 0)
+	_ = f.Set(ctx, "test2", "new", 0)
+	primary.setErr(nil)
+
+	assert.Eventually(t, func() bool {
+		return primary.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, f.Get(ctx, "test2").Err, cache.ErrCacheMiss)
+}
+
+func TestFailover_StaysUnhealthyOnDirtyKeyOverflowWithoutFlush(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(noFlushCache{primary}, []cache.Cache{fallback},
+		failover.WithInterval(10*time.Millisecond),
+		failover.WithMaxDirtyKeys(1),
+	)
+	t.Cleanup(func() { _ = f.Close() })
+
+	_ = primary.Set(ctx, "test2", "old", 0)
+	primary.setErr(errors.New("test error"))
+
+	_ = f.Set(ctx, "test1", "new", 0)
+	_ = f.Set(ctx, "test2", "new", 0)
+	primary.setErr(nil)
+
+	time.Sleep(50 * time.Millisecond)
+
+	str, err := f.Get(ctx, "test2").String()
+	require.NoError(t, err)
+	assert.Equal(t, "new", str)
+	assert.True(t, primary.has("test2"))
+}
+
+func TestFailover_CloseTwice(t *testing.T) {
+	f := failover.New(newFailingCache(), nil)
+
+	require.NoError(t, f.Close())
+	assert.NoError(t, f.Close())
+}
+
+func TestFailover_WithHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+
+	var mu sync.Mutex
+	healthy := false
+	f := failover.New(primary, []cache.Cache{fallback},
+		failover.WithInterval(10*time.Millisecond),
+		failover.WithHealthCheck(func(context.Context, cache.Cache) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if !healthy {
+				return errors.New("unhealthy")
+			}
+			return nil
+		}),
+	)
+	t.Cleanup(func() { _ = f.Close() })
+
+	primary.setErr(errors.New("test error"))
+	_ = f.Set(ctx, "test", "foobar", 0)
+	primary.setErr(nil)
+
+	time.Sleep(50 * time.Millisecond)
+	_ = f.Set(ctx, "test1", "foobar", 0)
+	assert.True(t, fallback.has("test1"))
+
+	mu.Lock()
+	healthy = true
+	mu.Unlock()
+
+	assert.Eventually(t, func() bool {
+		_ = f.Set(ctx, "test2", "foobar", 0)
+		return primary.has("test2")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFailover_WithFailureFunc(t *testing.T) {
+	ctx := context.Background()
+	primary, fallback := newFailingCache(), newFailingCache()
+	f := failover.New(primary, []cache.Cache{fallback}, failover.WithFailureFunc(func(err error) bool {
+		return false
+	}))
+	t.Cleanup(func() { _ = f.Close() })
+
+	primary.setErr(errors.New("test error"))
+
+	err := f.Set(ctx, "test", "foobar", 0)
+	assert.EqualError(t, err, "test error")
+	assert.False(t, fallback.has("test"))
+}
+
+func TestIsFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "nil",
+			err:  nil,
+			want: false,
+		},
+		{
+			name: "cache miss",
+			err:  cache.ErrCacheMiss,
+			want: false,
+		},
+		{
+			name: "not stored",
+			err:  cache.ErrNotStored,
+			want: false,
+		},
+		{
+			name: "context canceled",
+			err:  context.Canceled,
+			want: false,
+		},
+		{
+			name: "other",
+			err:  errors.New("test error"),
+			want: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			got := failover.IsFailure(test.err)
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+// failingCache fails all operations with the configured error.
+type failingCache struct {
+	*memory.Memory
+
+	mu  sync.Mutex
+	err error
+}
+
+func newFailingCache() *failingCache {
+	return &failingCache{Memory: memory.New()}
+}
+
+func (c *failingCache) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err = err
+}
+
+func (c *failingCache) fail() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *failingCache) has(key string) bool {
+	return c.Memory.Get(context.Background(), key).Err == nil
+}
+
+func (c *failingCache) Get(ctx context.Context, key string) cache.Item {
+	if err := c.fail(); err != nil {
+		return cache.NewItem(nil, nil, err)
+	}
+	return c.Memory.Get(ctx, key)
+}
+
+func (c *failingCache) GetMulti(ctx context.Context, keys ...string) ([]cache.Item, error) {
+	if err := c.fail(); err != nil {
+		return nil, err
+	}
+	return c.Memory.GetMulti(ctx, keys...)
+}
+
+func (c *failingCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	if err := c.fail(); err != nil {
+		return err
+	}
+	return c.Memory.Set(ctx, key, value, expire)
+}
+
+func (c *failingCache) Add(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	if err := c.fail(); err != nil {
+		return err
+	}
+	return c.Memory.Add(ctx, key, value, expire)
+}
+
+func (c *failingCache) Replace(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	if err := c.fail(); err != nil {
+		return err
+	}
+	return c.Memory.Replace(ctx, key, value, expire)
+}
+
+func (c *failingCache) Delete(ctx context.Context, key string) error {
+	if err := c.fail(); err != nil {
+		return err
+	}
+	return c.Memory.Delete(ctx, key)
+}
+
+func (c *failingCache) Inc(ctx context.Context, key string, value uint64) (int64, error) {
+	if err := c.fail(); err != nil {
+		return 0, err
+	}
+	return c.Memory.Inc(ctx, key, value)
+}
+
+func (c *failingCache) Dec(ctx context.Context, key string, value uint64) (int64, error) {
+	if err := c.fail(); err != nil {
+		return 0, err
+	}
+	return c.Memory.Dec(ctx, key, value)
+}
+
+// noFlushCache hides the Flush method of the cache.
+type noFlushCache struct {
+	cache.Cache
+}
+
+// blockingCache blocks the first Set until it is released.
+type blockingCache struct {
+	*failingCache
+
+	once  sync.Once
+	block chan struct{}
+}
+
+func (c *blockingCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	c.once.Do(func() {
+		c.block <- struct{}{}
+		<-c.block
+	})
+	return c.failingCache.Set(ctx, key, value, expire)
+}
@@ -0,0 +1,78 @@
+package cachetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/memory"
+)
+
+// CASCache is an in-memory cache supporting compare and swap and
+// conditional deletes. The CAS token of an item is its value, and the
+// conditional writes are only atomic with respect to each other.
+type CASCache struct {
+	*memory.Memory
+
+	mu sync.Mutex
+}
+
+// NewCASCache returns an in-memory CAS cache.
+func NewCASCache(opts ...memory.OptsFunc) *CASCache {
+	return &CASCache{Memory: memory.New(opts...)}
+}
+
+// Gets gets the item for the given key and its CAS token.
+func (c *CASCache) Gets(ctx context.Context, key string) cache.CASItem {
+	item := c.Get(ctx, key)
+	if item.Err != nil {
+		return cache.CASItem{Item: item}
+	}
+	b, _ := item.Bytes()
+	return cache.CASItem{Item: item, Token: string(b)}
+}
+
+// CompareAndSwap sets the item in the cache, but only if its value
+// is equal to the token.
+func (c *CASCache) CompareAndSwap(
+	ctx context.Context,
+	key string,
+	value interface{},
+	token cache.CASToken,
+	expire time.Duration,
+) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.compare(ctx, key, token, cache.ErrCASConflict); err != nil {
+		return err
+	}
+	return c.Set(ctx, key, value, expire)
+}
+
+// DeleteIfEqual deletes the item with the given key, but only if its
+// value is equal to value.
+func (c *CASCache) DeleteIfEqual(ctx context.Context, key string, value interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.compare(ctx, key, value, cache.ErrNotStored); err != nil {
+		return err
+	}
+	return c.Delete(ctx, key)
+}
+
+func (c *CASCache) compare(ctx context.Context, key string, want interface{}, errNotEqual error) error {
+	b, err := c.Get(ctx, key).Bytes()
+	switch {
+	case errors.Is(err, cache.ErrCacheMiss):
+		return cache.ErrCacheMiss
+	case err != nil:
+		return err
+	case string(b) != want:
+		return errNotEqual
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package cachetest implements conformance tests and test doubles shared by
+// the cache packages.
+package cachetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Counter tests that the cache counters behave identically across backends.
+func Counter(t *testing.T, c cache.CounterCache) {
+	t.Helper()
+
+	ctx := context.Background()
+	clamp := cache.CounterOptions{Initial: 10, Expire: time.Minute}
+	reject := cache.CounterOptions{Initial: 2, Underflow: cache.UnderflowReject}
+
+	steps := []struct {
+		name    string
+		key     string
+		delta   int64
+		opts    cache.CounterOptions
+		want    int64
+		wantErr error
+	}{
+		{name: "creates from initial", key: "counter-clamp", delta: 5, opts: clamp, want: 15},
+		{name: "ignores initial when existing", key: "counter-clamp", delta: 2, opts: clamp, want: 17},
+		{name: "clamps underflow", key: "counter-clamp", delta: -20, opts: clamp, want: 0},
+		{name: "clamps at zero", key: "counter-clamp", delta: -1, opts: clamp, want: 0},
+		{name: "increments from zero", key: "counter-clamp", delta: 3, opts: clamp, want: 3},
+		{
+			name: "creates when rejecting", key: "counter-reject", delta: -3, opts: reject,
+			want: 2, wantErr: cache.ErrNotStored,
+		},
+		{name: "decrements to zero", key: "counter-reject", delta: -2, opts: reject, want: 0},
+		{name: "rejects underflow", key: "counter-reject", delta: -1, opts: reject, want: 0, wantErr: cache.ErrNotStored},
+		{name: "handles zero delta", key: "counter-zero", want: 0},
+	}
+
+	for _, step := range steps {
+		_ = c.Delete(ctx, step.key)
+	}
+
+	for _, step := range steps {
+		n, err := c.IncWithOptions(ctx, step.key, step.delta, step.opts)
+
+		if step.wantErr != nil {
+			assert.ErrorIs(t, err, step.wantErr, step.name)
+		} else {
+			assert.NoError(t, err, step.name)
+		}
+		assert.Equal(t, step.want, n, step.name)
+
+		got, err := c.Get(ctx, step.key).Int64()
+		require.NoError(t, err, step.name)
+		assert.Equal(t, step.want, got, step.name)
+	}
+
+	_, err := c.IncWithOptions(ctx, "counter-negative", 1, cache.CounterOptions{Initial: -1})
+	assert.ErrorIs(t, err, cache.ErrNegativeInitial)
+
+	if tc, ok := c.(cache.TTLCache); ok {
+		ttl, err := tc.TTL(ctx, "counter-clamp")
+		if err == nil {
+			assert.Greater(t, ttl, time.Duration(0))
+			assert.LessOrEqual(t, ttl, time.Minute)
+		}
+		ttl, err = tc.TTL(ctx, "counter-reject")
+		if err == nil {
+			assert.Equal(t, cache.NoExpiration, ttl)
+		}
+	}
+}
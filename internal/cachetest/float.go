@@ -0,0 +1,56 @@
+package cachetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Float tests that the cache floating-point increments behave identically
+// across backends.
+func Float(t *testing.T, c cache.FloatCache) {
+	t.Helper()
+
+	ctx := context.Background()
+	_ = c.Delete(ctx, "float")
+
+	n, err := c.IncFloat(ctx, "float", 1.5)
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, n)
+
+	n, err = c.IncFloat(ctx, "float", -0.25)
+	require.NoError(t, err)
+	assert.Equal(t, 1.25, n)
+
+	got, err := c.Get(ctx, "float").Float64()
+	require.NoError(t, err)
+	assert.Equal(t, 1.25, got)
+
+	err = c.Set(ctx, "float", 3, time.Minute)
+	require.NoError(t, err)
+
+	n, err = c.IncFloat(ctx, "float", 0.1)
+	require.NoError(t, err)
+	assert.Equal(t, 3.1, n)
+
+	got, err = c.Get(ctx, "float").Float64()
+	require.NoError(t, err)
+	assert.Equal(t, 3.1, got)
+
+	if tc, ok := c.(cache.TTLCache); ok {
+		ttl, err := tc.TTL(ctx, "float")
+		if err == nil {
+			assert.Greater(t, ttl, time.Duration(0))
+		}
+	}
+
+	err = c.Set(ctx, "float", "foo", 0)
+	require.NoError(t, err)
+
+	_, err = c.IncFloat(ctx, "float", 1)
+	assert.Error(t, err)
+}
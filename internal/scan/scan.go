@@ -0,0 +1,146 @@
+// Package scan implements helpers for scanning keys.
+package scan
+
+import (
+	"context"
+	"time"
+
+	"github.com/hamba/cache/v2"
+)
+
+// DefaultBatchSize is the batch size used when none is configured.
+const DefaultBatchSize = 100
+
+// BatchSize returns the configured batch size, or the default.
+func BatchSize(opts cache.ScanOptions) int {
+	if opts.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return opts.BatchSize
+}
+
+// Throttle paces a scan, pausing after each batch of keys.
+type Throttle struct {
+	size  int
+	delay time.Duration
+	n     int
+}
+
+// NewThrottle returns a throttle for the scan options.
+func NewThrottle(opts cache.ScanOptions) *Throttle {
+	return &Throttle{
+		size:  BatchSize(opts),
+		delay: opts.Delay,
+	}
+}
+
+// Key records a scanned key, pausing at the end of each batch.
+func (t *Throttle) Key(ctx context.Context) error {
+	t.n++
+	if t.n < t.size {
+		return ctx.Err()
+	}
+	t.n = 0
+	return Wait(ctx, t.delay)
+}
+
+// Wait pauses for the delay, or until the context is done.
+func Wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Match reports whether the key matches the glob-style pattern.
+//
+// The pattern supports '*', '?', character classes such as [a-z] and
+// [^a], and '\' to escape the next character.
+func Match(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if Match(pattern, key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			var ok bool
+			pattern, ok = matchClass(pattern[1:], key[0])
+			if !ok {
+				return false
+			}
+			key = key[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+		}
+		pattern, key = pattern[1:], key[1:]
+	}
+	return len(key) == 0
+}
+
+// matchClass matches the character against the class at the start of the
+// pattern, returning the pattern following the class.
+func matchClass(pattern string, c byte) (string, bool) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	var match bool
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			match = match || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			match = match || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			match = match || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		// Skip the closing bracket.
+		pattern = pattern[1:]
+	}
+
+	return pattern, match != negate
+}
@@ -0,0 +1,75 @@
+package scan_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/scan"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{pattern: "*", key: "", want: true},
+		{pattern: "*", key: "foo", want: true},
+		{pattern: "foo", key: "foo", want: true},
+		{pattern: "foo", key: "foobar", want: false},
+		{pattern: "foo*", key: "foobar", want: true},
+		{pattern: "*bar", key: "foobar", want: true},
+		{pattern: "f*b*r", key: "foobar", want: true},
+		{pattern: "f*z", key: "foobar", want: false},
+		{pattern: "fo?bar", key: "foobar", want: true},
+		{pattern: "fo?bar", key: "fobar", want: false},
+		{pattern: "h[ae]llo", key: "hello", want: true},
+		{pattern: "h[ae]llo", key: "hillo", want: false},
+		{pattern: "h[^e]llo", key: "hallo", want: true},
+		{pattern: "h[^e]llo", key: "hello", want: false},
+		{pattern: "h[a-c]llo", key: "hbllo", want: true},
+		{pattern: "h[a-c]llo", key: "hdllo", want: false},
+		{pattern: `h\*llo`, key: "h*llo", want: true},
+		{pattern: `h\*llo`, key: "hello", want: false},
+		{pattern: `h[\]]llo`, key: "h]llo", want: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.pattern+"/"+test.key, func(t *testing.T) {
+			got := scan.Match(test.pattern, test.key)
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	assert.Equal(t, scan.DefaultBatchSize, scan.BatchSize(cache.ScanOptions{}))
+	assert.Equal(t, 10, scan.BatchSize(cache.ScanOptions{BatchSize: 10}))
+}
+
+func TestThrottle_Key(t *testing.T) {
+	ctx := context.Background()
+	th := scan.NewThrottle(cache.ScanOptions{BatchSize: 2, Delay: 20 * time.Millisecond})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, th.Key(ctx))
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestThrottle_KeyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	th := scan.NewThrottle(cache.ScanOptions{BatchSize: 2, Delay: time.Minute})
+
+	err := th.Key(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
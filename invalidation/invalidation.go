@@ -0,0 +1,467 @@
+// Package invalidation implements a two-layer cache that keeps local caches
+// of multiple instances consistent.
+//
+// Reads are served from the local cache, falling back to the remote cache.
+// Writes are sent to the remote cache, and the written keys are evicted from
+// the local cache and published to the other instances through a transport.
+// Each instance evicts the published keys from its own local cache.
+//
+// Each instance numbers its messages in sequence. When messages may have been
+// missed, because the subscription failed or a gap was detected in the
+// messages of another instance, the local cache is flushed. Messages that
+// arrive out of order, as concurrent writes are published concurrently, are
+// treated as a gap. Local items also
+// expire, bounding how long a stale item can be served.
+package invalidation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hamba/cache/v2"
+)
+
+// Message is an invalidation message.
+type Message struct {
+	// Source is the identifier of the publishing instance.
+	Source string `json:"source"`
+
+	// Seq is the sequence number of the message from its source,
+	// starting at 1.
+	Seq uint64 `json:"seq"`
+
+	// Keys are the invalidated keys.
+	Keys []string `json:"keys"`
+}
+
+// Subscription receives invalidation messages.
+type Subscription interface {
+	// Receive waits for the next message. An error indicates that
+	// messages may have been missed.
+	Receive(ctx context.Context) (Message, error)
+
+	// Close closes the subscription.
+	Close() error
+}
+
+// Transport publishes and subscribes to invalidation messages.
+type Transport interface {
+	// Publish publishes the message to all subscribers.
+	Publish(ctx context.Context, msg Message) error
+
+	// Subscribe subscribes to published messages.
+	Subscribe(ctx context.Context) (Subscription, error)
+}
+
+// Local represents the local cache layer.
+type Local interface {
+	cache.Cache
+
+	// Flush removes all items from the cache.
+	Flush(ctx context.Context) error
+}
+
+// OptsFunc represents an configuration function for Cache.
+type OptsFunc func(*Cache)
+
+// WithLocalTTL configures the expiration of items in the local cache.
+// The default is one minute.
+func WithLocalTTL(d time.Duration) OptsFunc {
+	return func(c *Cache) {
+		c.localTTL = d
+	}
+}
+
+// WithReconnectInterval configures the interval between attempts to
+// resubscribe after the subscription fails. The default is one second.
+func WithReconnectInterval(d time.Duration) OptsFunc {
+	return func(c *Cache) {
+		c.reconnect = d
+	}
+}
+
+// WithErrorFunc configures a function called with subscription errors.
+func WithErrorFunc(fn func(error)) OptsFunc {
+	return func(c *Cache) {
+		c.errFn = fn
+	}
+}
+
+// Cache is a two-layer cache, invalidating the local caches of
+// other instances on writes.
+type Cache struct {
+	local     Local
+	remote    cache.Cache
+	transport Transport
+	localTTL  time.Duration
+	reconnect time.Duration
+	errFn     func(error)
+
+	source string
+
+	seqMu sync.Mutex
+	seq   uint64
+
+	// last holds the highest sequence number received from each source.
+	last map[string]uint64
+
+	localMu sync.Mutex
+	// pending holds a token for each key being read, removed when the
+	// key is invalidated, so an invalidated read is not cached.
+	pending map[string]uint64
+	token   uint64
+
+	mu     sync.Mutex
+	sub    Subscription
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a two-layer cache, subscribing to invalidations
+// from other instances.
+func New(ctx context.Context, local Local, remote cache.Cache, t Transport, opts ...OptsFunc) (*Cache, error) {
+	source, err := newSource()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cache{
+		local:     local,
+		remote:    remote,
+		transport: t,
+		localTTL:  time.Minute,
+		reconnect: time.Second,
+		errFn:     func(error) {},
+		source:    source,
+		last:      map[string]uint64{},
+		pending:   map[string]uint64{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	sub, err := t.Subscribe(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("invalidation: subscribing: %w", err)
+	}
+
+	c.sub = sub
+	runCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go c.run(runCtx)
+
+	return c, nil
+}
+
+// Get gets the item for the given key.
+func (c *Cache) Get(ctx context.Context, key string) cache.Item {
+	if item := c.local.Get(ctx, key); item.Err == nil {
+		return item
+	}
+
+	keys := []string{key}
+	tokens := c.reserve(keys)
+	defer c.release(keys, tokens)
+
+	item := c.remote.Get(ctx, key)
+	if item.Err == nil {
+		c.store(ctx, key, tokens[0], item)
+	}
+	return item
+}
+
+// GetMulti gets the items for the given keys.
+func (c *Cache) GetMulti(ctx context.Context, keys ...string) ([]cache.Item, error) {
+	items, err := c.local.GetMulti(ctx, keys...)
+	if err != nil || len(items) != len(keys) {
+		items = make([]cache.Item, len(keys))
+		for i := range items {
+			items[i].Err = cache.ErrCacheMiss
+		}
+	}
+
+	var (
+		missed []string
+		idxs   []int
+	)
+	for i, item := range items {
+		if item.Err != nil {
+			missed = append(missed, keys[i])
+			idxs = append(idxs, i)
+		}
+	}
+	if len(missed) == 0 {
+		return items, nil
+	}
+
+	tokens := c.reserve(missed)
+	defer c.release(missed, tokens)
+
+	remote, err := c.remote.GetMulti(ctx, missed...)
+	if err != nil {
+		return nil, err
+	}
+	for j, item := range remote {
+		items[idxs[j]] = item
+		if item.Err == nil {
+			c.store(ctx, missed[j], tokens[j], item)
+		}
+	}
+
+	return items, nil
+}
+
+// reserve returns a pending token for each key.
+func (c *Cache) reserve(keys []string) []uint64 {
+	c.localMu.Lock()
+	defer c.localMu.Unlock()
+
+	tokens := make([]uint64, len(keys))
+	for i, k := range keys {
+		c.token++
+		tokens[i] = c.token
+		c.pending[k] = c.token
+	}
+	return tokens
+}
+
+// store sets the remote item in the local cache, unless the key
+// was invalidated since it was reserved.
+func (c *Cache) store(ctx context.Context, key string, token uint64, item cache.Item) {
+	b, err := item.Bytes()
+	if err != nil {
+		return
+	}
+
+	c.localMu.Lock()
+	defer c.localMu.Unlock()
+
+	if c.pending[key] != token {
+		return
+	}
+	delete(c.pending, key)
+	_ = c.local.Set(ctx, key, b, c.localTTL)
+}
+
+func (c *Cache) release(keys []string, tokens []uint64) {
+	c.localMu.Lock()
+	defer c.localMu.Unlock()
+
+	for i, k := range keys {
+		if c.pending[k] == tokens[i] {
+			delete(c.pending, k)
+		}
+	}
+}
+
+// evict removes the keys from the local cache.
+func (c *Cache) evict(ctx context.Context, keys ...string) {
+	c.localMu.Lock()
+	defer c.localMu.Unlock()
+
+	for _, k := range keys {
+		delete(c.pending, k)
+		_ = c.local.Delete(ctx, k)
+	}
+}
+
+// Set sets the item in the cache.
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	if err := c.remote.Set(ctx, key, value, expire); err != nil {
+		return err
+	}
+	return c.invalidate(ctx, key)
+}
+
+// Add sets the item in the cache, but only if the key does not already exist.
+func (c *Cache) Add(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	if err := c.remote.Add(ctx, key, value, expire); err != nil {
+		return err
+	}
+	return c.invalidate(ctx, key)
+}
+
+// Replace sets the item in the cache, but only if the key already exists.
+func (c *Cache) Replace(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	if err := c.remote.Replace(ctx, key, value, expire); err != nil {
+		return err
+	}
+	return c.invalidate(ctx, key)
+}
+
+// Delete deletes the item with the given key.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	err := c.remote.Delete(ctx, key)
+	if ierr := c.invalidate(ctx, key); err == nil {
+		err = ierr
+	}
+	return err
+}
+
+// Inc increments a key by the value.
+func (c *Cache) Inc(ctx context.Context, key string, value uint64) (int64, error) {
+	n, err := c.remote.Inc(ctx, key, value)
+	if err != nil {
+		return n, err
+	}
+	return n, c.invalidate(ctx, key)
+}
+
+// Dec decrements a key by the value.
+func (c *Cache) Dec(ctx context.Context, key string, value uint64) (int64, error) {
+	n, err := c.remote.Dec(ctx, key, value)
+	if err != nil {
+		return n, err
+	}
+	return n, c.invalidate(ctx, key)
+}
+
+// Invalidate evicts the keys from the local caches of all instances.
+func (c *Cache) Invalidate(ctx context.Context, keys ...string) error {
+	return c.invalidate(ctx, keys...)
+}
+
+func (c *Cache) invalidate(ctx context.Context, keys ...string) error {
+	c.evict(ctx, keys...)
+
+	// The sequence number is taken under the lock, but publishing is not,
+	// so a slow transport does not block other writers. Receivers flush on
+	// messages that arrive out of order.
+	c.seqMu.Lock()
+	c.seq++
+	seq := c.seq
+	c.seqMu.Unlock()
+
+	msg := Message{
+		Source: c.source,
+		Seq:    seq,
+		Keys:   keys,
+	}
+	if err := c.transport.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("invalidation: publishing: %w", err)
+	}
+	return nil
+}
+
+// Close stops receiving invalidations.
+func (c *Cache) Close() error {
+	c.cancel()
+
+	// Closing the subscription unblocks receiving.
+	c.mu.Lock()
+	_ = c.sub.Close()
+	c.mu.Unlock()
+
+	c.wg.Wait()
+	return nil
+}
+
+// run receives invalidations, resubscribing when the subscription fails.
+func (c *Cache) run(ctx context.Context) {
+	defer c.wg.Done()
+
+	for {
+		c.mu.Lock()
+		sub := c.sub
+		c.mu.Unlock()
+
+		err := c.receive(ctx, sub)
+		_ = sub.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		c.errFn(err)
+
+		// Messages may have been missed while the subscription was down.
+		c.flush(ctx)
+
+		for {
+			t := time.NewTimer(c.reconnect)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+
+			sub, err = c.transport.Subscribe(ctx)
+			if err == nil {
+				break
+			}
+			c.errFn(fmt.Errorf("invalidation: subscribing: %w", err))
+		}
+
+		if !c.setSubscription(ctx, sub) {
+			return
+		}
+
+		// Flush again, as messages may have been missed before subscribing.
+		c.flush(ctx)
+	}
+}
+
+// setSubscription sets the current subscription, closing it
+// instead if the cache is closed.
+func (c *Cache) setSubscription(ctx context.Context, sub Subscription) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ctx.Err() != nil {
+		_ = sub.Close()
+		return false
+	}
+	c.sub = sub
+	return true
+}
+
+func (c *Cache) receive(ctx context.Context, sub Subscription) error {
+	for {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		if msg.Source == c.source {
+			continue
+		}
+
+		last, seen := c.last[msg.Source]
+		if seen && msg.Seq <= last {
+			// A late or redelivered message does not advance the sequence.
+			c.evict(ctx, msg.Keys...)
+			continue
+		}
+		c.last[msg.Source] = msg.Seq
+		if seen && msg.Seq != last+1 {
+			c.flush(ctx)
+			continue
+		}
+
+		c.evict(ctx, msg.Keys...)
+	}
+}
+
+func (c *Cache) flush(ctx context.Context) {
+	c.localMu.Lock()
+	c.pending = map[string]uint64{}
+	err := c.local.Flush(ctx)
+	c.localMu.Unlock()
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		c.errFn(fmt.Errorf("invalidation: flushing local cache: %w", err))
+	}
+}
+
+func newSource() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,382 @@
+package invalidation_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/invalidation"
+	"github.com/hamba/cache/v2/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTest = errors.New("test")
+
+func TestCache_Implements(t *testing.T) {
+	c := newTestCache(t, memory.New(), memory.New(), newBroker())
+
+	assert.Implements(t, (*cache.Cache)(nil), c)
+}
+
+func TestCache_ReadsThroughLocal(t *testing.T) {
+	ctx := context.Background()
+	remote, local := memory.New(), memory.New()
+	c := newTestCache(t, local, remote, newBroker())
+
+	_ = remote.Set(ctx, "a", "1", 0)
+	_ = remote.Set(ctx, "b", "2", 0)
+
+	str, err := c.Get(ctx, "a").String()
+	require.NoError(t, err)
+	assert.Equal(t, "1", str)
+
+	items, err := c.GetMulti(ctx, "a", "b", "_")
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	str, err = items[1].String()
+	require.NoError(t, err)
+	assert.Equal(t, "2", str)
+	assert.ErrorIs(t, items[2].Err, cache.ErrCacheMiss)
+
+	assert.Equal(t, 2, local.Len())
+}
+
+func TestCache_InvalidatesOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	remote, b := memory.New(), newBroker()
+	local := memory.New()
+	writer := newTestCache(t, memory.New(), remote, b)
+	reader := newTestCache(t, local, remote, b)
+
+	err := writer.Set(ctx, "test", "foo", 0)
+	require.NoError(t, err)
+
+	str, err := reader.Get(ctx, "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", str)
+
+	err = writer.Set(ctx, "test", "bar", 0)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return local.Len() == 0 }, time.Second, time.Millisecond)
+	str, err = reader.Get(ctx, "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", str)
+
+	_ = reader.Get(ctx, "test")
+	err = writer.Delete(ctx, "test")
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return local.Len() == 0 }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, reader.Get(ctx, "test").Err, cache.ErrCacheMiss)
+}
+
+func TestCache_FlushesOnMissedMessages(t *testing.T) {
+	ctx := context.Background()
+	remote, b, local := memory.New(), newBroker(), memory.New()
+	_ = newTestCache(t, local, remote, b)
+
+	_ = local.Set(ctx, "a", "1", 0)
+	_ = local.Set(ctx, "b", "2", 0)
+
+	err := b.Publish(ctx, invalidation.Message{Source: "other", Seq: 1, Keys: []string{"a"}})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return local.Len() == 1 }, time.Second, time.Millisecond)
+
+	err = b.Publish(ctx, invalidation.Message{Source: "other", Seq: 3, Keys: []string{"c"}})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return local.Len() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestCache_SequentialWritesDoNotFlush(t *testing.T) {
+	ctx := context.Background()
+	remote, b := memory.New(), newBroker()
+	local := &flushCounter{Memory: memory.New()}
+	writer := newTestCache(t, memory.New(), remote, b)
+	_ = newTestCache(t, local, remote, b)
+
+	for i := 0; i < 100; i++ {
+		_ = writer.Set(ctx, "test", "foobar", 0)
+	}
+
+	_ = local.Set(ctx, "done", "1", 0)
+	err := writer.Invalidate(ctx, "done")
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return local.Len() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, 0, local.flushes())
+}
+
+func TestCache_SlowPublishDoesNotBlockWrites(t *testing.T) {
+	ctx := context.Background()
+	b := &blockingBroker{broker: newBroker(), block: make(chan struct{})}
+	c := newTestCache(t, memory.New(), memory.New(), b)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_ = c.Set(ctx, "slow", "foobar", 0)
+	}()
+	<-b.block
+
+	err := c.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	b.block <- struct{}{}
+	<-done
+}
+
+func TestCache_DoesNotFillInvalidatedRead(t *testing.T) {
+	ctx := context.Background()
+	b, local := newBroker(), memory.New()
+	remote := &blockingCache{Cache: memory.New(), read: make(chan struct{}), release: make(chan struct{})}
+	c := newTestCache(t, local, remote, b)
+
+	_ = remote.Cache.Set(ctx, "test", "old", 0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_ = c.Get(ctx, "test")
+	}()
+	<-remote.read
+
+	_ = local.Set(ctx, "sync", "1", 0)
+	err := b.Publish(ctx, invalidation.Message{Source: "other", Seq: 1, Keys: []string{"test", "sync"}})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return local.Len() == 0 }, time.Second, time.Millisecond)
+
+	close(remote.release)
+	<-done
+
+	assert.Equal(t, 0, local.Len())
+}
+
+func TestCache_ResubscribesAndFlushesOnError(t *testing.T) {
+	ctx := context.Background()
+	remote, b, local := memory.New(), newBroker(), memory.New()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	_ = newTestCache(t, local, remote, b, invalidation.WithErrorFunc(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		errs = append(errs, err)
+	}))
+	errCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(errs)
+	}
+
+	_ = local.Set(ctx, "a", "1", 0)
+	b.setSubscribeErr(errTest)
+	b.fail(errTest)
+
+	// The local cache is flushed before subscribing again fails.
+	assert.Eventually(t, func() bool { return errCount() >= 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, 0, local.Len())
+
+	_ = local.Set(ctx, "a", "1", 0)
+	b.setSubscribeErr(nil)
+
+	assert.Eventually(t, func() bool { return b.subscribers() == 1 }, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return local.Len() == 0 }, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ErrorIs(t, errs[0], errTest)
+	assert.ErrorIs(t, errs[1], errTest)
+}
+
+func TestCache_Close(t *testing.T) {
+	b := newBroker()
+	c, err := invalidation.New(context.Background(), memory.New(), memory.New(), b)
+	require.NoError(t, err)
+
+	err = c.Close()
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, b.subscribers())
+}
+
+func TestNew_SubscribeError(t *testing.T) {
+	b := newBroker()
+	b.setSubscribeErr(errTest)
+
+	_, err := invalidation.New(context.Background(), memory.New(), memory.New(), b)
+
+	assert.ErrorIs(t, err, errTest)
+}
+
+func newTestCache(t *testing.T, local invalidation.Local, remote cache.Cache, b invalidation.Transport, opts ...invalidation.OptsFunc) *invalidation.Cache {
+	t.Helper()
+
+	opts = append([]invalidation.OptsFunc{invalidation.WithReconnectInterval(time.Millisecond)}, opts...)
+	c, err := invalidation.New(context.Background(), local, remote, b, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+// flushCounter counts the flushes of the local cache.
+type flushCounter struct {
+	*memory.Memory
+
+	mu sync.Mutex
+	n  int
+}
+
+func (c *flushCounter) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+
+	return c.Memory.Flush(ctx)
+}
+
+func (c *flushCounter) flushes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.n
+}
+
+// blockingCache blocks reads after reading the item until released.
+type blockingCache struct {
+	cache.Cache
+
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingCache) Get(ctx context.Context, key string) cache.Item {
+	item := c.Cache.Get(ctx, key)
+	c.read <- struct{}{}
+	<-c.release
+	return item
+}
+
+// blockingBroker blocks publishing the key "slow" until it is released.
+type blockingBroker struct {
+	*broker
+
+	block chan struct{}
+}
+
+func (b *blockingBroker) Publish(ctx context.Context, msg invalidation.Message) error {
+	if len(msg.Keys) == 1 && msg.Keys[0] == "slow" {
+		b.block <- struct{}{}
+		<-b.block
+	}
+	return b.broker.Publish(ctx, msg)
+}
+
+// broker is an in-memory invalidation transport.
+type broker struct {
+	mu           sync.Mutex
+	subs         map[*subscription]struct{}
+	subscribeErr error
+}
+
+func newBroker() *broker {
+	return &broker{subs: map[*subscription]struct{}{}}
+}
+
+func (b *broker) Publish(_ context.Context, msg invalidation.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		sub.msgs <- msg
+	}
+	return nil
+}
+
+func (b *broker) Subscribe(_ context.Context) (invalidation.Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribeErr != nil {
+		return nil, b.subscribeErr
+	}
+
+	sub := &subscription{
+		b:      b,
+		msgs:   make(chan invalidation.Message, 100),
+		errs:   make(chan error, 1),
+		closed: make(chan struct{}),
+	}
+	b.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+func (b *broker) setSubscribeErr(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribeErr = err
+}
+
+// fail fails all subscriptions with the error.
+func (b *broker) fail(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		sub.errs <- err
+		delete(b.subs, sub)
+	}
+}
+
+func (b *broker) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subs)
+}
+
+type subscription struct {
+	b      *broker
+	msgs   chan invalidation.Message
+	errs   chan error
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (s *subscription) Receive(ctx context.Context) (invalidation.Message, error) {
+	select {
+	case msg := <-s.msgs:
+		return msg, nil
+	case err := <-s.errs:
+		return invalidation.Message{}, err
+	case <-s.closed:
+		return invalidation.Message{}, errors.New("subscription closed")
+	case <-ctx.Done():
+		return invalidation.Message{}, ctx.Err()
+	}
+}
+
+func (s *subscription) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+
+		s.b.mu.Lock()
+		delete(s.b.subs, s)
+		s.b.mu.Unlock()
+	})
+	return nil
+}
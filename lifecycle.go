@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Pinger represents a cache instance that can check its connectivity.
+type Pinger interface {
+	Cache
+
+	// Ping checks that the cache servers are reachable.
+	Ping(ctx context.Context) error
+}
+
+// Flusher represents a cache instance that can remove all its items.
+type Flusher interface {
+	Cache
+
+	// Flush removes all items from the cache.
+	Flush(ctx context.Context) error
+}
+
+// Closer represents a cache instance holding connections.
+type Closer interface {
+	Cache
+
+	// Close releases the connections of the cache.
+	Close() error
+}
+
+// Ready pings the caches concurrently, returning an error if any of
+// them is not ready. Caches that are not a Pinger are considered ready.
+//
+// It is intended for readiness checks.
+func Ready(ctx context.Context, caches ...Cache) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(caches))
+	for i, c := range caches {
+		p, ok := c.(Pinger)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, p Pinger) {
+			defer wg.Done()
+
+			errs[i] = p.Ping(ctx)
+		}(i, p)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("cache: cache %d is not ready: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hamba/cache/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	err := cache.Ready(context.Background(), &pingingCache{}, &recordingCache{})
+
+	assert.NoError(t, err)
+}
+
+func TestReady_NotReady(t *testing.T) {
+	err := cache.Ready(context.Background(), &pingingCache{}, &pingingCache{err: errTest})
+
+	assert.ErrorIs(t, err, errTest)
+	assert.EqualError(t, err, "cache: cache 1 is not ready: test")
+}
+
+type pingingCache struct {
+	recordingCache
+
+	err error
+}
+
+func (c *pingingCache) Ping(context.Context) error {
+	return c.err
+}
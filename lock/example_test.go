@@ -0,0 +1,34 @@
+package lock_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/hamba/cache/v2/lock"
+	"github.com/hamba/cache/v2/redis"
+)
+
+func ExampleLocker_Lock() {
+	c, err := redis.New("redis://localhost:6379")
+	if err != nil {
+		// Handle error
+	}
+
+	l := lock.New(c, lock.WithTTL(10*time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	lease, err := l.Lock(ctx, "report")
+	if err != nil {
+		// Handle error
+	}
+	defer func() { _ = lease.Release(context.Background()) }()
+
+	select {
+	case <-lease.Lost():
+		// Stop working, the lock is held by another owner
+	default:
+		_ = lease.Fence()
+	}
+}
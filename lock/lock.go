@@ -0,0 +1,300 @@
+// Package lock implements distributed locks on top of a cache.
+//
+// A lock is held as a lease: a key holding a random owner token and the
+// fencing token of the lease, that expires unless it is renewed. Leases are
+// renewed in the background, and are only renewed and released while the key
+// still holds the owner token, so a lease that has expired and been acquired
+// by another owner is never modified.
+//
+// Each acquired lease has a fencing token, a number that increases with every
+// acquisition of the lock. The token is taken before the lock is acquired, so
+// a lease always has a higher token than the leases acquired before it. Failed
+// attempts also take a token, so tokens are not consecutive. Passing the
+// fencing token to the protected resource allows it to reject writes from
+// owners whose lease has since been lost.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hamba/cache/v2"
+)
+
+var (
+	// ErrNotAcquired is returned if a lock is held by another owner.
+	ErrNotAcquired = errors.New("lock: not acquired")
+
+	// ErrNotHeld is returned if a lease is no longer held by its owner.
+	ErrNotHeld = errors.New("lock: not held")
+)
+
+// Cache represents a cache that can hold locks.
+type Cache interface {
+	cache.CASCache
+
+	// DeleteIfEqual deletes the item with the given key, but only if its
+	// value is equal to value. ErrNotStored is returned if the value is not
+	// equal, and ErrCacheMiss if the key does not exist.
+	DeleteIfEqual(ctx context.Context, k string, v interface{}) error
+}
+
+// OptsFunc represents a configuration function for Locker.
+type OptsFunc func(*Locker)
+
+// WithTTL configures the lifetime of a lease. The default is 30 seconds.
+// Lifetimes under a second are rounded up to a second, as memcache
+// expirations are in whole seconds.
+func WithTTL(d time.Duration) OptsFunc {
+	return func(l *Locker) {
+		l.ttl = d
+	}
+}
+
+// WithRenewInterval configures the interval between lease renewals. The
+// default is a third of the lease lifetime. A negative interval disables
+// renewal.
+func WithRenewInterval(d time.Duration) OptsFunc {
+	return func(l *Locker) {
+		l.renew = d
+	}
+}
+
+// WithRetryInterval configures the interval between attempts to acquire
+// a held lock. The default is 100 milliseconds.
+func WithRetryInterval(d time.Duration) OptsFunc {
+	return func(l *Locker) {
+		l.retry = d
+	}
+}
+
+// WithPrefix configures the prefix of the cache keys. The default is "lock:".
+func WithPrefix(prefix string) OptsFunc {
+	return func(l *Locker) {
+		l.prefix = prefix
+	}
+}
+
+// Locker acquires locks.
+type Locker struct {
+	cache  Cache
+	ttl    time.Duration
+	renew  time.Duration
+	retry  time.Duration
+	prefix string
+}
+
+// New returns a locker.
+func New(c Cache, opts ...OptsFunc) *Locker {
+	l := &Locker{
+		cache:  c,
+		ttl:    30 * time.Second,
+		retry:  100 * time.Millisecond,
+		prefix: "lock:",
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.ttl < time.Second {
+		// A sub-second expiration would be stored as never expiring.
+		l.ttl = time.Second
+	}
+	if l.renew == 0 {
+		l.renew = l.ttl / 3
+	}
+
+	return l
+}
+
+// Lock acquires the lock with the given key, waiting until it is
+// acquired or the context is done.
+func (l *Locker) Lock(ctx context.Context, key string) (*Lease, error) {
+	for {
+		lease, err := l.TryLock(ctx, key)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lease, err
+		}
+
+		t := time.NewTimer(l.retry)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// TryLock acquires the lock with the given key, returning
+// ErrNotAcquired if it is held by another owner.
+func (l *Locker) TryLock(ctx context.Context, key string) (*Lease, error) {
+	owner, err := newOwner()
+	if err != nil {
+		return nil, err
+	}
+
+	// The fence is taken before acquiring, so an owner that stalls
+	// after acquiring cannot take a fence after the next owner.
+	k := l.prefix + key
+	fence, err := l.nextFence(ctx, k+":fence")
+	if err != nil {
+		return nil, err
+	}
+
+	value := owner + ":" + strconv.FormatInt(fence, 10)
+	err = l.cache.Add(ctx, k, value, l.ttl)
+	switch {
+	case errors.Is(err, cache.ErrNotStored):
+		return nil, ErrNotAcquired
+	case err != nil:
+		return nil, err
+	}
+
+	lease := &Lease{
+		cache: l.cache,
+		key:   k,
+		owner: owner,
+		value: value,
+		fence: fence,
+		ttl:   l.ttl,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+		lost:  make(chan struct{}),
+	}
+	if l.renew > 0 {
+		go lease.keepAlive(l.renew)
+	} else {
+		close(lease.done)
+	}
+
+	return lease, nil
+}
+
+// nextFence increments the fencing token counter, which never expires.
+func (l *Locker) nextFence(ctx context.Context, key string) (int64, error) {
+	if cc, ok := l.cache.(cache.CounterCache); ok {
+		return cc.IncWithOptions(ctx, key, 1, cache.CounterOptions{})
+	}
+
+	if err := l.cache.Add(ctx, key, "0", 0); err != nil && !errors.Is(err, cache.ErrNotStored) {
+		return 0, err
+	}
+	return l.cache.Inc(ctx, key, 1)
+}
+
+// Lease is an acquired lock.
+type Lease struct {
+	cache Cache
+	key   string
+	owner string
+	value string
+	fence int64
+	ttl   time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+
+	lostOnce sync.Once
+	lost     chan struct{}
+}
+
+// Owner returns the random owner token of the lease.
+func (l *Lease) Owner() string {
+	return l.owner
+}
+
+// Fence returns the fencing token of the lease.
+func (l *Lease) Fence() int64 {
+	return l.fence
+}
+
+// Lost returns a channel that is closed when the lease is lost,
+// either because it expired or is held by another owner.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Renew extends the lifetime of the lease, returning ErrNotHeld
+// if the lease is no longer held.
+func (l *Lease) Renew(ctx context.Context) error {
+	item := l.cache.Gets(ctx, l.key)
+	if errors.Is(item.Err, cache.ErrCacheMiss) {
+		return l.markLost()
+	}
+	if item.Err != nil {
+		return item.Err
+	}
+	if v, _ := item.String(); v != l.value {
+		return l.markLost()
+	}
+
+	err := l.cache.CompareAndSwap(ctx, l.key, l.value, item.Token, l.ttl)
+	if errors.Is(err, cache.ErrCASConflict) || errors.Is(err, cache.ErrCacheMiss) {
+		return l.markLost()
+	}
+	return err
+}
+
+// Release stops renewing the lease and releases the lock, returning
+// ErrNotHeld if the lease is no longer held.
+func (l *Lease) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+
+	err := l.cache.DeleteIfEqual(ctx, l.key, l.value)
+	if errors.Is(err, cache.ErrNotStored) || errors.Is(err, cache.ErrCacheMiss) {
+		return l.markLost()
+	}
+	return err
+}
+
+// keepAlive renews the lease until it is stopped or lost. The lease is
+// considered lost when it could not be renewed before it would expire.
+func (l *Lease) keepAlive(interval time.Duration) {
+	defer close(l.done)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-l.lost:
+			return
+		case <-t.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := l.Renew(ctx)
+		cancel()
+
+		switch {
+		case err == nil:
+			renewed = time.Now()
+		case errors.Is(err, ErrNotHeld), time.Since(renewed) >= l.ttl:
+			_ = l.markLost()
+			return
+		}
+	}
+}
+
+func (l *Lease) markLost() error {
+	l.lostOnce.Do(func() { close(l.lost) })
+	return ErrNotHeld
+}
+
+func newOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,205 @@
+package lock_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/cachetest"
+	"github.com/hamba/cache/v2/lock"
+	"github.com/hamba/cache/v2/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocker_TryLock(t *testing.T) {
+	ctx := context.Background()
+	c := cachetest.NewCASCache()
+	l := lock.New(c)
+
+	lease, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+	assert.Len(t, lease.Owner(), 32)
+	assert.Equal(t, int64(1), lease.Fence())
+	str, err := c.Get(ctx, "lock:test").String()
+	require.NoError(t, err)
+	assert.Equal(t, lease.Owner()+":1", str)
+
+	_, err = l.TryLock(ctx, "test")
+	assert.ErrorIs(t, err, lock.ErrNotAcquired)
+
+	err = lease.Release(ctx)
+	require.NoError(t, err)
+	assert.ErrorIs(t, c.Get(ctx, "lock:test").Err, cache.ErrCacheMiss)
+
+	next, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+	assert.NotEqual(t, lease.Owner(), next.Owner())
+	assert.Greater(t, next.Fence(), lease.Fence())
+
+	err = next.Release(ctx)
+	require.NoError(t, err)
+}
+
+func TestLocker_TryLockFencesInAcquisitionOrder(t *testing.T) {
+	ctx := context.Background()
+	c := &addHookCache{CASCache: cachetest.NewCASCache()}
+	l := lock.New(c)
+
+	// The next owner acquires the lock as soon as the first lease expires.
+	var next *lock.Lease
+	c.onAdd = func(key string) {
+		c.onAdd = nil
+		_ = c.Delete(ctx, key)
+
+		var err error
+		next, err = l.TryLock(ctx, "test")
+		require.NoError(t, err)
+	}
+
+	lease, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+
+	require.NotNil(t, next)
+	assert.Greater(t, next.Fence(), lease.Fence())
+}
+
+func TestLocker_RoundsUpSubSecondTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := cachetest.NewCASCache(memory.WithClock(func() time.Time { return now }))
+	l := lock.New(c, lock.WithTTL(100*time.Millisecond), lock.WithRenewInterval(-1))
+
+	_, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+
+	now = now.Add(900 * time.Millisecond)
+	_, err = l.TryLock(ctx, "test")
+	assert.ErrorIs(t, err, lock.ErrNotAcquired)
+
+	now = now.Add(200 * time.Millisecond)
+	_, err = l.TryLock(ctx, "test")
+	assert.NoError(t, err)
+}
+
+func TestLocker_LockWaitsForRelease(t *testing.T) {
+	ctx := context.Background()
+	l := lock.New(cachetest.NewCASCache(), lock.WithRetryInterval(5*time.Millisecond))
+
+	lease, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = lease.Release(ctx)
+	}()
+
+	lockCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	next, err := l.Lock(lockCtx, "test")
+	require.NoError(t, err)
+	assert.Greater(t, next.Fence(), lease.Fence())
+}
+
+func TestLocker_LockRespectsContext(t *testing.T) {
+	l := lock.New(cachetest.NewCASCache(), lock.WithRetryInterval(5*time.Millisecond))
+
+	_, err := l.TryLock(context.Background(), "test")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = l.Lock(ctx, "test")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLease_RenewsInBackground(t *testing.T) {
+	ctx := context.Background()
+	l := lock.New(cachetest.NewCASCache(), lock.WithRenewInterval(10*time.Millisecond))
+
+	lease, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+
+	time.Sleep(150 * time.Millisecond)
+
+	_, err = l.TryLock(ctx, "test")
+	assert.ErrorIs(t, err, lock.ErrNotAcquired)
+	select {
+	case <-lease.Lost():
+		t.Fatal("lease lost")
+	default:
+	}
+
+	err = lease.Release(ctx)
+	assert.NoError(t, err)
+}
+
+func TestLease_ExpiresWithoutRenewal(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := cachetest.NewCASCache(memory.WithClock(func() time.Time { return now }))
+	l := lock.New(c, lock.WithTTL(time.Second), lock.WithRenewInterval(-1))
+
+	lease, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Second)
+
+	next, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+
+	err = lease.Release(ctx)
+	assert.ErrorIs(t, err, lock.ErrNotHeld)
+
+	err = lease.Renew(ctx)
+	assert.ErrorIs(t, err, lock.ErrNotHeld)
+
+	err = next.Release(ctx)
+	assert.NoError(t, err)
+}
+
+func TestLease_LostWhenTakenOver(t *testing.T) {
+	ctx := context.Background()
+	c := cachetest.NewCASCache()
+	l := lock.New(c, lock.WithRenewInterval(5*time.Millisecond))
+
+	lease, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "lock:test", "other", 0)
+	require.NoError(t, err)
+
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease not lost")
+	}
+
+	err = lease.Release(ctx)
+	assert.ErrorIs(t, err, lock.ErrNotHeld)
+	str, err := c.Get(ctx, "lock:test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "other", str)
+}
+
+// addHookCache calls onAdd after a lock key is added.
+type addHookCache struct {
+	*cachetest.CASCache
+
+	onAdd func(key string)
+}
+
+func (c *addHookCache) Add(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	if err := c.CASCache.Add(ctx, key, value, expire); err != nil {
+		return err
+	}
+	if c.onAdd != nil && !strings.HasSuffix(key, ":fence") {
+		c.onAdd(key)
+	}
+	return nil
+}
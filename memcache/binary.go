@@ -0,0 +1,499 @@
+package memcache
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/hamba/cache/v2/internal/decoder"
+)
+
+// Binary protocol magic bytes.
+const (
+	magicRequest  = 0x80
+	magicResponse = 0x81
+)
+
+// Binary protocol opcodes.
+const (
+	opGet       = 0x00
+	opSet       = 0x01
+	opAdd       = 0x02
+	opReplace   = 0x03
+	opDelete    = 0x04
+	opIncrement = 0x05
+	opDecrement = 0x06
+	opFlush     = 0x08
+	opNoop      = 0x0a
+	opVersion   = 0x0b
+	opGetKQ     = 0x0d
+	opStat      = 0x10
+	opTouch     = 0x1c
+	opGAT       = 0x1d
+	opSASLAuth  = 0x21
+)
+
+// Binary protocol response statuses.
+const (
+	statusOK          = 0x00
+	statusKeyNotFound = 0x01
+	statusKeyExists   = 0x02
+	statusNotStored   = 0x05
+	statusAuthError   = 0x20
+)
+
+const headerLen = 24
+
+// ErrAuthFailed is returned if SASL authentication with a server failed.
+var ErrAuthFailed = errors.New("memcache: authentication failed")
+
+// BinaryConfig configures a memcache binary protocol client.
+type BinaryConfig struct {
+	// Username and Password enable SASL PLAIN authentication
+	// when Username is set.
+	Username string
+	Password string
+
+	// TLSConfig enables TLS when set. The server name defaults to the
+	// host of the server address. Server host names are resolved when
+	// the servers are set, so ServerName must be set for certificates
+	// issued to a host name.
+	TLSConfig *tls.Config
+
+	// Dialer is used to connect to the servers. Defaults to a net.Dialer.
+	Dialer DialFunc
+}
+
+// NewBinary creates a new Memcache instance using the memcache binary
+// protocol, supporting SASL authentication and TLS.
+//
+// Multiple servers can be given as a comma separated list.
+func NewBinary(uri string, cfg BinaryConfig, opts ...OptsFunc) (*Memcache, error) {
+	ss := &memcache.ServerList{}
+	if err := ss.SetServers(strings.Split(uri, ",")...); err != nil {
+		return nil, err
+	}
+
+	return NewBinaryWithSelector(ss, cfg, opts...), nil
+}
+
+// NewBinaryWithSelector creates a new Memcache instance using the memcache
+// binary protocol and the given server selector.
+func NewBinaryWithSelector(ss memcache.ServerSelector, cfg BinaryConfig, opts ...OptsFunc) *Memcache {
+	c := &binaryClient{
+		pool: newPool(ss, cfg.Dialer, cfg.TLSConfig, opts),
+	}
+	c.pool.resumable = isResumable
+	if cfg.Username != "" {
+		c.pool.onConnect = func(cn *conn) error {
+			return auth(cn, cfg.Username, cfg.Password)
+		}
+	}
+
+	return &Memcache{
+		client: c,
+		enc:    memcacheEncoder,
+		dec:    decoder.StringDecoder{},
+	}
+}
+
+type binaryHeader struct {
+	magic    uint8
+	opcode   uint8
+	keyLen   uint16
+	extraLen uint8
+	status   uint16
+	bodyLen  uint32
+	opaque   uint32
+	cas      uint64
+}
+
+type binaryPacket struct {
+	binaryHeader
+
+	extras []byte
+	key    string
+	value  []byte
+}
+
+func writePacket(cn *conn, p binaryPacket) error {
+	var hdr [headerLen]byte
+	hdr[0] = magicRequest
+	hdr[1] = p.opcode
+	binary.BigEndian.PutUint16(hdr[2:4], uint16(len(p.key)))
+	hdr[4] = uint8(len(p.extras))
+	binary.BigEndian.PutUint32(hdr[8:12], uint32(len(p.extras)+len(p.key)+len(p.value)))
+	binary.BigEndian.PutUint32(hdr[12:16], p.opaque)
+	binary.BigEndian.PutUint64(hdr[16:24], p.cas)
+
+	if _, err := cn.rw.Write(hdr[:]); err != nil {
+		return err
+	}
+	if _, err := cn.rw.Write(p.extras); err != nil {
+		return err
+	}
+	if _, err := cn.rw.WriteString(p.key); err != nil {
+		return err
+	}
+	_, err := cn.rw.Write(p.value)
+	return err
+}
+
+func readPacket(cn *conn) (binaryPacket, error) {
+	var hdr [headerLen]byte
+	if _, err := io.ReadFull(cn.rw, hdr[:]); err != nil {
+		return binaryPacket{}, err
+	}
+	if hdr[0] != magicResponse {
+		return binaryPacket{}, fmt.Errorf("memcache: invalid response magic %#x", hdr[0])
+	}
+
+	p := binaryPacket{binaryHeader: binaryHeader{
+		magic:    hdr[0],
+		opcode:   hdr[1],
+		keyLen:   binary.BigEndian.Uint16(hdr[2:4]),
+		extraLen: hdr[4],
+		status:   binary.BigEndian.Uint16(hdr[6:8]),
+		bodyLen:  binary.BigEndian.Uint32(hdr[8:12]),
+		opaque:   binary.BigEndian.Uint32(hdr[12:16]),
+		cas:      binary.BigEndian.Uint64(hdr[16:24]),
+	}}
+	if int(p.extraLen)+int(p.keyLen) > int(p.bodyLen) {
+		return binaryPacket{}, errors.New("memcache: invalid response body length")
+	}
+
+	body := make([]byte, p.bodyLen)
+	if _, err := io.ReadFull(cn.rw, body); err != nil {
+		return binaryPacket{}, err
+	}
+	p.extras = body[:p.extraLen]
+	p.key = string(body[p.extraLen : int(p.extraLen)+int(p.keyLen)])
+	p.value = body[int(p.extraLen)+int(p.keyLen):]
+
+	return p, nil
+}
+
+// roundTrip writes the request and reads its response.
+func roundTrip(cn *conn, req binaryPacket) (binaryPacket, error) {
+	if err := writePacket(cn, req); err != nil {
+		return binaryPacket{}, err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return binaryPacket{}, err
+	}
+	return readPacket(cn)
+}
+
+// binaryClient is a memcache client using the binary protocol.
+type binaryClient struct {
+	pool *pool
+}
+
+func (c *binaryClient) Get(key string) (*memcache.Item, error) {
+	item, _, err := c.gets(key)
+	return item, err
+}
+
+// gets gets the item for the given key with its cas value.
+func (c *binaryClient) gets(key string) (*memcache.Item, uint64, error) {
+	var (
+		item *memcache.Item
+		cas  uint64
+	)
+	err := c.pool.withKeyConn(key, func(cn *conn) error {
+		resp, err := roundTrip(cn, binaryPacket{binaryHeader: binaryHeader{opcode: opGet}, key: key})
+		if err != nil {
+			return err
+		}
+		if err = statusError(resp.status, memcache.ErrCacheMiss); err != nil {
+			return err
+		}
+
+		item = &memcache.Item{Key: key, Value: resp.value, Flags: flags(resp.extras)}
+		cas = resp.cas
+		return nil
+	})
+	return item, cas, err
+}
+
+func (c *binaryClient) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	var mu sync.Mutex
+	items := make(map[string]*memcache.Item, len(keys))
+	err := c.pool.withKeysConn(keys, func(cn *conn, keys []string) error {
+		return getMulti(cn, keys, func(item *memcache.Item) {
+			mu.Lock()
+			items[item.Key] = item
+			mu.Unlock()
+		})
+	})
+	return items, err
+}
+
+// getMulti pipelines a quiet get for each key, terminated by a noop.
+// Quiet gets only respond on a hit.
+func getMulti(cn *conn, keys []string, cb func(*memcache.Item)) error {
+	for _, k := range keys {
+		if err := writePacket(cn, binaryPacket{binaryHeader: binaryHeader{opcode: opGetKQ}, key: k}); err != nil {
+			return err
+		}
+	}
+	if err := writePacket(cn, binaryPacket{binaryHeader: binaryHeader{opcode: opNoop}}); err != nil {
+		return err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		resp, err := readPacket(cn)
+		if err != nil {
+			return err
+		}
+		if resp.opcode == opNoop {
+			return nil
+		}
+		if resp.status != statusOK {
+			continue
+		}
+		cb(&memcache.Item{Key: resp.key, Value: resp.value, Flags: flags(resp.extras)})
+	}
+}
+
+func (c *binaryClient) Set(item *memcache.Item) error {
+	return c.store(opSet, item, 0)
+}
+
+func (c *binaryClient) Add(item *memcache.Item) error {
+	return c.store(opAdd, item, 0)
+}
+
+func (c *binaryClient) Replace(item *memcache.Item) error {
+	return c.store(opReplace, item, 0)
+}
+
+// cas sets the item, but only if its cas value has not changed.
+func (c *binaryClient) cas(item *memcache.Item, cas uint64) error {
+	return c.store(opSet, item, cas)
+}
+
+func (c *binaryClient) store(op uint8, item *memcache.Item, cas uint64) error {
+	extras := make([]byte, 8)
+	binary.BigEndian.PutUint32(extras[0:4], item.Flags)
+	binary.BigEndian.PutUint32(extras[4:8], uint32(item.Expiration))
+
+	return c.pool.withKeyConn(item.Key, func(cn *conn) error {
+		resp, err := roundTrip(cn, binaryPacket{
+			binaryHeader: binaryHeader{opcode: op, cas: cas},
+			extras:       extras,
+			key:          item.Key,
+			value:        item.Value,
+		})
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case op == opAdd && resp.status == statusKeyExists,
+			op == opReplace && resp.status == statusKeyNotFound:
+			return memcache.ErrNotStored
+		}
+		return statusError(resp.status, memcache.ErrCacheMiss)
+	})
+}
+
+func (c *binaryClient) Delete(key string) error {
+	return c.deleteCAS(key, 0)
+}
+
+// deleteCAS deletes the key, but only if its cas value has not changed.
+func (c *binaryClient) deleteCAS(key string, cas uint64) error {
+	return c.pool.withKeyConn(key, func(cn *conn) error {
+		resp, err := roundTrip(cn, binaryPacket{binaryHeader: binaryHeader{opcode: opDelete, cas: cas}, key: key})
+		if err != nil {
+			return err
+		}
+		return statusError(resp.status, memcache.ErrCacheMiss)
+	})
+}
+
+func (c *binaryClient) Touch(key string, seconds int32) error {
+	extras := make([]byte, 4)
+	binary.BigEndian.PutUint32(extras, uint32(seconds))
+
+	return c.pool.withKeyConn(key, func(cn *conn) error {
+		resp, err := roundTrip(cn, binaryPacket{
+			binaryHeader: binaryHeader{opcode: opTouch},
+			extras:       extras,
+			key:          key,
+		})
+		if err != nil {
+			return err
+		}
+		return statusError(resp.status, memcache.ErrCacheMiss)
+	})
+}
+
+// getAndTouch gets the item for the given key and updates its expiration.
+func (c *binaryClient) getAndTouch(key string, seconds int32) (*memcache.Item, error) {
+	extras := make([]byte, 4)
+	binary.BigEndian.PutUint32(extras, uint32(seconds))
+
+	var item *memcache.Item
+	err := c.pool.withKeyConn(key, func(cn *conn) error {
+		resp, err := roundTrip(cn, binaryPacket{
+			binaryHeader: binaryHeader{opcode: opGAT},
+			extras:       extras,
+			key:          key,
+		})
+		if err != nil {
+			return err
+		}
+		if err = statusError(resp.status, memcache.ErrCacheMiss); err != nil {
+			return err
+		}
+
+		item = &memcache.Item{Key: key, Value: resp.value, Flags: flags(resp.extras)}
+		return nil
+	})
+	return item, err
+}
+
+func (c *binaryClient) Increment(key string, delta uint64) (uint64, error) {
+	return c.incrDecr(opIncrement, key, delta, 0)
+}
+
+func (c *binaryClient) Decrement(key string, delta uint64) (uint64, error) {
+	return c.incrDecr(opDecrement, key, delta, 0)
+}
+
+// decrCAS decrements the key, but only if its cas value has not changed.
+func (c *binaryClient) decrCAS(key string, delta, cas uint64) (uint64, error) {
+	return c.incrDecr(opDecrement, key, delta, cas)
+}
+
+func (c *binaryClient) incrDecr(op uint8, key string, delta, cas uint64) (uint64, error) {
+	// An expiration of all ones fails the operation if the key does not
+	// exist, matching the text protocol.
+	extras := make([]byte, 20)
+	binary.BigEndian.PutUint64(extras[0:8], delta)
+	binary.BigEndian.PutUint32(extras[16:20], 0xffffffff)
+
+	var v uint64
+	err := c.pool.withKeyConn(key, func(cn *conn) error {
+		resp, err := roundTrip(cn, binaryPacket{
+			binaryHeader: binaryHeader{opcode: op, cas: cas},
+			extras:       extras,
+			key:          key,
+		})
+		if err != nil {
+			return err
+		}
+		if err = statusError(resp.status, memcache.ErrCacheMiss); err != nil {
+			return err
+		}
+		if len(resp.value) != 8 {
+			return errors.New("memcache: invalid counter response")
+		}
+
+		v = binary.BigEndian.Uint64(resp.value)
+		return nil
+	})
+	return v, err
+}
+
+// Ping checks that each server responds to a version request.
+func (c *binaryClient) Ping() error {
+	return c.pool.each(func(cn *conn) error {
+		resp, err := roundTrip(cn, binaryPacket{binaryHeader: binaryHeader{opcode: opVersion}})
+		if err != nil {
+			return err
+		}
+		return statusError(resp.status, memcache.ErrCacheMiss)
+	})
+}
+
+// FlushAll removes all items from each server.
+func (c *binaryClient) FlushAll() error {
+	return c.pool.each(func(cn *conn) error {
+		resp, err := roundTrip(cn, binaryPacket{binaryHeader: binaryHeader{opcode: opFlush}})
+		if err != nil {
+			return err
+		}
+		return statusError(resp.status, memcache.ErrCacheMiss)
+	})
+}
+
+// closeIdle closes the idle connections.
+func (c *binaryClient) closeIdle() error {
+	return c.pool.closeIdle()
+}
+
+// auth authenticates the connection using SASL PLAIN.
+func auth(cn *conn, username, password string) error {
+	resp, err := roundTrip(cn, binaryPacket{
+		binaryHeader: binaryHeader{opcode: opSASLAuth},
+		key:          "PLAIN",
+		value:        []byte("\x00" + username + "\x00" + password),
+	})
+	if err != nil {
+		return err
+	}
+	if resp.status == statusAuthError {
+		return ErrAuthFailed
+	}
+	return statusError(resp.status, memcache.ErrCacheMiss)
+}
+
+// statusError converts a response status into an error, using missErr
+// when the key was not found.
+func statusError(status uint16, missErr error) error {
+	switch status {
+	case statusOK:
+		return nil
+	case statusKeyNotFound:
+		return missErr
+	case statusKeyExists:
+		return memcache.ErrCASConflict
+	case statusNotStored:
+		return memcache.ErrNotStored
+	default:
+		return &StatusError{Status: status}
+	}
+}
+
+// StatusError is returned when a binary protocol server responds
+// with an unexpected status.
+type StatusError struct {
+	Status uint16
+}
+
+// Error returns the error message.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("memcache: server error status %#x", e.Status)
+}
+
+// isResumable determines if the connection can be reused after the error.
+func isResumable(err error) bool {
+	var statusErr *StatusError
+	switch {
+	case errors.Is(err, memcache.ErrCacheMiss),
+		errors.Is(err, memcache.ErrCASConflict),
+		errors.Is(err, memcache.ErrNotStored),
+		errors.Is(err, memcache.ErrMalformedKey),
+		errors.As(err, &statusErr):
+		return true
+	}
+	return false
+}
+
+func flags(extras []byte) uint32 {
+	if len(extras) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(extras[0:4])
+}
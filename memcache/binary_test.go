@@ -0,0 +1,589 @@
+package memcache_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/cachetest"
+	"github.com/hamba/cache/v2/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemcacheBinaryCache(t *testing.T) {
+	if skipMemcache {
+		t.Skipf("skipping test; no running server at %s", testMemcachedServer)
+	}
+
+	c, err := memcache.NewBinary(testMemcachedServer, memcache.BinaryConfig{})
+	require.NoError(t, err)
+
+	testCache(t, c)
+}
+
+func TestMemcacheBinaryCache_SASLAndTLS(t *testing.T) {
+	cert, pool := newTestCert(t)
+	addr := newBinaryStandIn(t, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+
+	dials := 0
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{
+		Username:  "user",
+		Password:  "pass",
+		TLSConfig: &tls.Config{RootCAs: pool, ServerName: "localhost", MinVersion: tls.VersionTLS12},
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dials++
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}, memcache.WithTimeout(time.Second))
+	require.NoError(t, err)
+
+	testCache(t, c)
+	assert.Equal(t, 1, dials)
+}
+
+func TestMemcacheBinaryCache_TLSWithoutServerName(t *testing.T) {
+	cert, pool := newTestCert(t)
+	addr := newBinaryStandIn(t, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+
+	cfg := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{
+		Username:  "user",
+		Password:  "pass",
+		TLSConfig: cfg,
+	}, memcache.WithTimeout(time.Second))
+	require.NoError(t, err)
+
+	err = c.Set(context.Background(), "test", "foobar", 0)
+	require.NoError(t, err)
+	str, err := c.Get(context.Background(), "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+	assert.Empty(t, cfg.ServerName)
+}
+
+func TestMemcacheBinaryCache_CompareAndSwap(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	testCASCache(t, c)
+}
+
+func TestMemcacheBinaryCache_Touch(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	testTouchCache(t, c)
+
+	_, err = c.TTL(context.Background(), "test")
+	assert.ErrorIs(t, err, memcache.ErrNotSupported)
+}
+
+func TestMemcacheBinaryCache_Lock(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	testLockCache(t, c)
+}
+
+func TestMemcacheBinaryCache_Multi(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	testMultiCache(t, c)
+}
+
+func TestMemcacheBinaryCache_Counter(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	cachetest.Counter(t, c)
+}
+
+func TestMemcacheBinaryCache_Float(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	cachetest.Float(t, c)
+}
+
+func TestMemcacheBinaryCache_Batch(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+	ctx := context.Background()
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "counter", 1, 0)
+	require.NoError(t, err)
+
+	b := cache.NewBatch()
+	inc := b.Inc("counter", 2)
+	set := b.Set("marker", "bar", time.Minute)
+	get := b.Get("marker")
+
+	items := b.Exec(ctx, c)
+
+	require.Len(t, items, 3)
+	i, err := items[inc].Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), i)
+	assert.NoError(t, items[set].Err)
+	str, err := items[get].String()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", str)
+}
+
+func TestMemcacheBinaryCache_ScanNotSupported(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	err = c.Scan(context.Background(), "*", cache.ScanOptions{}, func(string) error { return nil })
+
+	assert.ErrorIs(t, err, memcache.ErrNotSupported)
+}
+
+func TestMemcacheBinaryCache_Lifecycle(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	testLifecycle(t, c)
+}
+
+func TestMemcacheBinaryCache_Stats(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+	ctx := context.Background()
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.StatsCache)(nil), c)
+
+	err = c.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	stats, err := c.Stats(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Items)
+	assert.Equal(t, time.Minute, stats.Uptime)
+	assert.Equal(t, 1, stats.Pool.TotalConns)
+	assert.Equal(t, 1, stats.Pool.IdleConns)
+}
+
+func TestMemcacheBinaryCache_AuthFailed(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{Username: "user", Password: "wrong"})
+	require.NoError(t, err)
+
+	err = c.Set(context.Background(), "test", "foobar", 0)
+	assert.ErrorIs(t, err, memcache.ErrAuthFailed)
+}
+
+func TestMemcacheBinaryCache_RequiresAuth(t *testing.T) {
+	addr := newBinaryStandIn(t, nil)
+
+	c, err := memcache.NewBinary(addr, memcache.BinaryConfig{})
+	require.NoError(t, err)
+
+	err = c.Set(context.Background(), "test", "foobar", 0)
+	var statusErr *memcache.StatusError
+	assert.ErrorAs(t, err, &statusErr)
+}
+
+func TestNewBinary_InvalidURI(t *testing.T) {
+	_, err := memcache.NewBinary("test", memcache.BinaryConfig{})
+
+	assert.Error(t, err)
+}
+
+func testCache(t *testing.T, c cache.Cache) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	assert.Implements(t, (*cache.Cache)(nil), c)
+
+	// Set
+	err := c.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	// Get
+	str, err := c.Get(ctx, "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+
+	_, err = c.Get(ctx, "_").String()
+	assert.EqualError(t, err, cache.ErrCacheMiss.Error())
+
+	// Add
+	_ = c.Delete(ctx, "test1")
+	err = c.Add(ctx, "test1", "foobar", 0)
+	require.NoError(t, err)
+
+	err = c.Add(ctx, "test1", "foobar", 0)
+	assert.EqualError(t, err, cache.ErrNotStored.Error())
+
+	// Replace
+	err = c.Replace(ctx, "test1", "foobar", 0)
+	require.NoError(t, err)
+
+	err = c.Replace(ctx, "_", "foobar", 0)
+	assert.EqualError(t, err, cache.ErrNotStored.Error())
+
+	// GetMulti
+	v, err := c.GetMulti(ctx, "test", "test1", "_")
+	require.NoError(t, err)
+	assert.Len(t, v, 3)
+	str, err = v[0].String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+	assert.EqualError(t, v[2].Err, "cache: miss")
+
+	// Delete
+	err = c.Delete(ctx, "test1")
+	require.NoError(t, err)
+
+	_, err = c.Get(ctx, "test1").String()
+	assert.Error(t, err)
+
+	// Inc
+	err = c.Set(ctx, "test2", 1, 0)
+	require.NoError(t, err)
+
+	i, err := c.Inc(ctx, "test2", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), i)
+
+	// Dec
+	err = c.Set(ctx, "test2", 1, 0)
+	require.NoError(t, err)
+
+	i, err = c.Dec(ctx, "test2", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), i)
+}
+
+func testCASCache(t *testing.T, c cache.CASCache) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	err := c.Set(ctx, "test", "foo", 0)
+	require.NoError(t, err)
+
+	got := c.Gets(ctx, "test")
+	require.NoError(t, got.Err)
+	str, err := got.String()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", str)
+
+	err = c.CompareAndSwap(ctx, "test", "bar", got.Token, 0)
+	require.NoError(t, err)
+
+	err = c.CompareAndSwap(ctx, "test", "baz", got.Token, 0)
+	assert.ErrorIs(t, err, cache.ErrCASConflict)
+
+	str, err = c.Get(ctx, "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", str)
+
+	got = c.Gets(ctx, "test")
+	require.NoError(t, got.Err)
+	err = c.Delete(ctx, "test")
+	require.NoError(t, err)
+
+	err = c.CompareAndSwap(ctx, "test", "baz", got.Token, 0)
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	got = c.Gets(ctx, "test")
+	assert.ErrorIs(t, got.Err, cache.ErrCacheMiss)
+	assert.Nil(t, got.Token)
+
+	err = c.CompareAndSwap(ctx, "test", "baz", "invalid", 0)
+	assert.Error(t, err)
+}
+
+func newTestCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+// newBinaryStandIn starts a minimal in-memory memcached binary protocol
+// server that requires SASL PLAIN authentication as user:pass.
+func newBinaryStandIn(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if cfg != nil {
+		ln = tls.NewListener(ln, cfg)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &binaryStandIn{data: map[string][]byte{}, cass: map[string]uint64{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+type binaryStandIn struct {
+	mu   sync.Mutex
+	cas  uint64
+	data map[string][]byte
+	cass map[string]uint64
+}
+
+func (s *binaryStandIn) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	authed := false
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		var hdr [24]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return
+		}
+		req := binaryStandInRequest{
+			op:  hdr[1],
+			cas: binary.BigEndian.Uint64(hdr[16:24]),
+		}
+		keyLen := int(binary.BigEndian.Uint16(hdr[2:4]))
+		extraLen := int(hdr[4])
+		body := make([]byte, binary.BigEndian.Uint32(hdr[8:12]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+		req.extras = body[:extraLen]
+		req.key = string(body[extraLen : extraLen+keyLen])
+		req.value = body[extraLen+keyLen:]
+
+		var resp binaryStandInResponse
+		switch {
+		case req.op == 0x21:
+			authed = string(req.value) == "\x00user\x00pass"
+			if !authed {
+				resp.status = 0x20
+			}
+		case !authed:
+			resp.status = 0x20
+		case req.op == 0x10:
+			s.writeStats(w)
+		default:
+			resp = s.handle(req)
+		}
+
+		if resp.quiet {
+			continue
+		}
+		writeBinaryResponse(w, req.op, resp)
+		if req.op == 0x0d {
+			continue
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeStats writes a response per statistic, followed by the
+// terminating response written by the caller.
+func (s *binaryStandIn) writeStats(w *bufio.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := map[string]string{
+		"curr_items": strconv.Itoa(len(s.data)),
+		"uptime":     "60",
+	}
+	for k, v := range stats {
+		writeBinaryResponse(w, 0x10, binaryStandInResponse{key: k, value: []byte(v)})
+	}
+}
+
+type binaryStandInRequest struct {
+	op     byte
+	cas    uint64
+	extras []byte
+	key    string
+	value  []byte
+}
+
+type binaryStandInResponse struct {
+	status uint16
+	cas    uint64
+	key    string
+	value  []byte
+	quiet  bool
+}
+
+func (s *binaryStandIn) handle(req binaryStandInRequest) binaryStandInResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.data[req.key]
+	switch req.op {
+	case 0x00, 0x0d:
+		if !ok {
+			return binaryStandInResponse{status: 0x01, quiet: req.op == 0x0d}
+		}
+		resp := binaryStandInResponse{value: v, cas: s.cass[req.key]}
+		if req.op == 0x0d {
+			resp.key = req.key
+		}
+		return resp
+	case 0x01, 0x02, 0x03:
+		switch {
+		case req.op == 0x02 && ok:
+			return binaryStandInResponse{status: 0x02}
+		case req.op == 0x03 && !ok:
+			return binaryStandInResponse{status: 0x01}
+		case req.cas != 0 && !ok:
+			return binaryStandInResponse{status: 0x01}
+		case req.cas != 0 && req.cas != s.cass[req.key]:
+			return binaryStandInResponse{status: 0x02}
+		}
+		s.store(req.key, req.value)
+		return binaryStandInResponse{cas: s.cass[req.key]}
+	case 0x04:
+		switch {
+		case !ok:
+			return binaryStandInResponse{status: 0x01}
+		case req.cas != 0 && req.cas != s.cass[req.key]:
+			return binaryStandInResponse{status: 0x02}
+		}
+		delete(s.data, req.key)
+		delete(s.cass, req.key)
+		return binaryStandInResponse{}
+	case 0x05, 0x06:
+		switch {
+		case !ok:
+			return binaryStandInResponse{status: 0x01}
+		case req.cas != 0 && req.cas != s.cass[req.key]:
+			return binaryStandInResponse{status: 0x02}
+		}
+		n, _ := strconv.ParseUint(string(v), 10, 64)
+		delta := binary.BigEndian.Uint64(req.extras[0:8])
+		switch {
+		case req.op == 0x05:
+			n += delta
+		case delta > n:
+			n = 0
+		default:
+			n -= delta
+		}
+		s.store(req.key, []byte(strconv.FormatUint(n, 10)))
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, n)
+		return binaryStandInResponse{value: b, cas: s.cass[req.key]}
+	case 0x1c, 0x1d:
+		if !ok {
+			return binaryStandInResponse{status: 0x01}
+		}
+		if req.op == 0x1c {
+			return binaryStandInResponse{}
+		}
+		return binaryStandInResponse{value: v, cas: s.cass[req.key]}
+	case 0x0a:
+		return binaryStandInResponse{}
+	case 0x0b:
+		return binaryStandInResponse{value: []byte("1.6.0")}
+	case 0x08:
+		s.data = map[string][]byte{}
+		s.cass = map[string]uint64{}
+		return binaryStandInResponse{}
+	default:
+		return binaryStandInResponse{status: 0x81}
+	}
+}
+
+func (s *binaryStandIn) store(key string, value []byte) {
+	s.cas++
+	s.data[key] = value
+	s.cass[key] = s.cas
+}
+
+func writeBinaryResponse(w *bufio.Writer, op byte, resp binaryStandInResponse) {
+	extras := []byte(nil)
+	if (op == 0x00 || op == 0x0d || op == 0x1d) && resp.status == 0 {
+		extras = make([]byte, 4)
+	}
+
+	var hdr [24]byte
+	hdr[0] = 0x81
+	hdr[1] = op
+	binary.BigEndian.PutUint16(hdr[2:4], uint16(len(resp.key)))
+	hdr[4] = uint8(len(extras))
+	binary.BigEndian.PutUint16(hdr[6:8], resp.status)
+	binary.BigEndian.PutUint32(hdr[8:12], uint32(len(extras)+len(resp.key)+len(resp.value)))
+	binary.BigEndian.PutUint64(hdr[16:24], resp.cas)
+
+	_, _ = w.Write(hdr[:])
+	_, _ = w.Write(extras)
+	_, _ = w.WriteString(resp.key)
+	_, _ = w.Write(resp.value)
+}
@@ -0,0 +1,167 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/hamba/cache/v2"
+)
+
+// counterMaxAttempts is the maximum number of attempts of a counter update.
+const counterMaxAttempts = 10
+
+// counter is a client supporting conditional counter updates.
+type counter interface {
+	Add(ctx context.Context, key string, value interface{}, expire time.Duration) error
+	Inc(ctx context.Context, key string, value uint64) (int64, error)
+	Dec(ctx context.Context, key string, value uint64) (int64, error)
+	Gets(ctx context.Context, key string) cache.CASItem
+	decCAS(key string, value uint64, token cache.CASToken) (int64, error)
+}
+
+// incWithOptions adds the delta to the counter, creating it with add
+// when the update misses.
+func incWithOptions(ctx context.Context, c counter, key string, delta int64, opts cache.CounterOptions) (int64, error) {
+	if opts.Initial < 0 {
+		return 0, cache.ErrNegativeInitial
+	}
+	opts.Expire = roundUpSeconds(opts.Expire)
+
+	for i := 0; i < counterMaxAttempts; i++ {
+		n, err := applyDelta(ctx, c, key, delta, opts.Underflow)
+		switch {
+		case errors.Is(err, cache.ErrCASConflict):
+			continue
+		case !isMiss(err):
+			return n, err
+		}
+
+		// A concurrent creation of the counter is fine.
+		err = c.Add(ctx, key, opts.Initial, opts.Expire)
+		if err != nil && !errors.Is(err, cache.ErrNotStored) {
+			return 0, err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return 0, fmt.Errorf("memcache: counter update failed after %d attempts: %w", counterMaxAttempts, cache.ErrCASConflict)
+}
+
+func applyDelta(ctx context.Context, c counter, key string, delta int64, underflow cache.Underflow) (int64, error) {
+	switch {
+	case delta >= 0:
+		return c.Inc(ctx, key, uint64(delta))
+	case underflow != cache.UnderflowReject:
+		// The server clamps decrements at zero.
+		return c.Dec(ctx, key, uint64(-delta))
+	}
+
+	item := c.Gets(ctx, key)
+	if item.Err != nil {
+		return 0, item.Err
+	}
+	n, err := item.Int64()
+	if err != nil {
+		return 0, err
+	}
+	if n+delta < 0 {
+		return n, cache.ErrNotStored
+	}
+
+	return c.decCAS(key, uint64(-delta), item.Token)
+}
+
+// incFloat adds the delta to the key using a compare and swap loop,
+// keeping the remaining lifetime of the item when it is known.
+func incFloat(ctx context.Context, c cache.CASCache, key string, delta float64) (float64, error) {
+	for i := 0; i < counterMaxAttempts; i++ {
+		n, err := casFloat(ctx, c, key, delta)
+		if !errors.Is(err, cache.ErrCASConflict) && !isMiss(err) {
+			return n, err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return 0, fmt.Errorf("memcache: float increment failed after %d attempts: %w",
+		counterMaxAttempts, cache.ErrCASConflict)
+}
+
+func casFloat(ctx context.Context, c cache.CASCache, key string, delta float64) (float64, error) {
+	item := c.Gets(ctx, key)
+	switch {
+	case isMiss(item.Err):
+		err := c.Add(ctx, key, formatFloat(delta), 0)
+		if errors.Is(err, cache.ErrNotStored) {
+			return 0, cache.ErrCASConflict
+		}
+		return delta, err
+	case item.Err != nil:
+		return 0, item.Err
+	}
+
+	n, err := item.Float64()
+	if err != nil {
+		return 0, err
+	}
+	n += delta
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, errors.New("memcache: increment would produce NaN or infinity")
+	}
+
+	expire, err := remainingTTL(ctx, c, key)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, c.CompareAndSwap(ctx, key, formatFloat(n), item.Token, expire)
+}
+
+// remainingTTL returns the expiration that keeps the remaining lifetime of
+// the item, or zero if it does not expire or its lifetime is unknown.
+func remainingTTL(ctx context.Context, c cache.Cache, key string) (time.Duration, error) {
+	tc, ok := c.(cache.TTLCache)
+	if !ok {
+		return 0, nil
+	}
+
+	ttl, err := tc.TTL(ctx, key)
+	switch {
+	case errors.Is(err, ErrNotSupported), ttl == cache.NoExpiration:
+		return 0, nil
+	case err != nil:
+		return 0, err
+	case ttl < time.Second:
+		// Memcache expirations are in seconds, so round up to keep the item expiring.
+		return time.Second, nil
+	}
+	return ttl, nil
+}
+
+// roundUpSeconds rounds a positive expiration up to whole seconds, as
+// memcache expirations are in seconds and would otherwise be truncated,
+// leaving sub-second expirations not expiring at all.
+func roundUpSeconds(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return (d + time.Second - 1).Truncate(time.Second)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func isMiss(err error) bool {
+	return errors.Is(err, cache.ErrCacheMiss) || errors.Is(err, memcache.ErrCacheMiss)
+}
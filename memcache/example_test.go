@@ -2,6 +2,7 @@ package memcache_test
 
 import (
 	"context"
+	"crypto/tls"
 	"time"
 
 	"github.com/hamba/cache/v2/memcache"
@@ -17,3 +18,67 @@ func ExampleNew() {
 
 	_, _ = i.Float64()
 }
+
+func ExampleNewBinary() {
+	c, err := memcache.NewBinary("localhost:11211", memcache.BinaryConfig{
+		Username:  "user",
+		Password:  "pass",
+		TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+	}, memcache.WithTimeout(10*time.Millisecond))
+	if err != nil {
+		// Handle error
+	}
+
+	i := c.Get(context.Background(), "foobar")
+	if i.Err != nil {
+		// Handle error
+	}
+
+	_, _ = i.Float64()
+}
+
+func ExampleNewWithSelector() {
+	k, err := memcache.NewKetama(
+		memcache.Server{Addr: "10.0.0.1:11211", Weight: 2},
+		memcache.Server{Addr: "10.0.0.2:11211", Weight: 1},
+	)
+	if err != nil {
+		// Handle error
+	}
+
+	c := memcache.NewWithSelector(k, memcache.WithTimeout(10*time.Millisecond))
+
+	// The servers can be updated without recreating the adapter.
+	err = k.SetServers(
+		memcache.Server{Addr: "10.0.0.1:11211", Weight: 2},
+		memcache.Server{Addr: "10.0.0.3:11211", Weight: 1},
+	)
+	if err != nil {
+		// Handle error
+	}
+
+	i := c.Get(context.Background(), "foobar")
+	if i.Err != nil {
+		// Handle error
+	}
+
+	_, _ = i.Float64()
+}
+
+func ExampleNewMeta() {
+	c, err := memcache.NewMeta("localhost:11211", memcache.MetaConfig{}, memcache.WithTimeout(10*time.Millisecond))
+	if err != nil {
+		// Handle error
+	}
+
+	// Only one client wins the right to recache the item before it expires.
+	i := c.GetMeta(context.Background(), "foobar", memcache.MetaGetOptions{RecacheTTL: 30 * time.Second})
+	if i.Err != nil {
+		// Handle error
+	}
+	if i.Win {
+		// Recache the item
+	}
+
+	_, _ = i.Float64()
+}
@@ -0,0 +1,177 @@
+package memcache
+
+import (
+	"crypto/md5" //nolint:gosec // Required for ketama compatibility.
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+const (
+	ketamaPointsPerServer = 160
+	ketamaPointsPerHash   = 4
+	defaultPort           = 11211
+)
+
+// Server is a weighted memcache server.
+type Server struct {
+	// Addr is the server address, either host:port or a unix socket path.
+	Addr string
+
+	// Weight is the relative weight of the server. A weight of zero
+	// or less is treated as 1.
+	Weight int
+}
+
+type ketamaPoint struct {
+	hash uint32
+	addr net.Addr
+}
+
+// Ketama is a weighted consistent hashing server selector compatible
+// with libmemcached's ketama implementation, as used by the PHP memcached
+// extension and the Java spymemcached client in libmemcached mode.
+//
+// It is safe for concurrent use, and the servers can be updated at runtime.
+type Ketama struct {
+	mu     sync.RWMutex
+	addrs  []net.Addr
+	points []ketamaPoint
+}
+
+// NewKetama returns a ketama server selector for the given servers.
+func NewKetama(servers ...Server) (*Ketama, error) {
+	k := &Ketama{}
+	if err := k.SetServers(servers...); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// SetServers changes the set of servers. If an error is returned,
+// no changes are made.
+func (k *Ketama) SetServers(servers ...Server) error {
+	addrs := make([]net.Addr, 0, len(servers))
+	total := 0
+	for _, s := range servers {
+		addr, err := resolveAddr(s.Addr)
+		if err != nil {
+			return err
+		}
+		addrs = append(addrs, addr)
+		total += weight(s)
+	}
+
+	points := make([]ketamaPoint, 0, len(servers)*ketamaPointsPerServer)
+	for i, s := range servers {
+		// The float32 arithmetic matches libmemcached.
+		pct := float32(weight(s)) / float32(total)
+		hashes := int(math.Floor(float64(pct*ketamaPointsPerServer/ketamaPointsPerHash*float32(len(servers))) + 0.0000000001))
+
+		prefix := ketamaPrefix(s.Addr)
+		for j := 0; j < hashes; j++ {
+			digest := md5.Sum([]byte(prefix + strconv.Itoa(j))) //nolint:gosec // Required for ketama compatibility.
+			for h := 0; h < ketamaPointsPerHash; h++ {
+				points = append(points, ketamaPoint{
+					hash: ketamaHash(digest, h),
+					addr: addrs[i],
+				})
+			}
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.addrs = addrs
+	k.points = points
+	return nil
+}
+
+// PickServer returns the server address for the given key.
+func (k *Ketama) PickServer(key string) (net.Addr, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	switch len(k.addrs) {
+	case 0:
+		return nil, memcache.ErrNoServers
+	case 1:
+		return k.addrs[0], nil
+	}
+
+	hash := ketamaHash(md5.Sum([]byte(key)), 0) //nolint:gosec // Required for ketama compatibility.
+	i := sort.Search(len(k.points), func(i int) bool {
+		return k.points[i].hash >= hash
+	})
+	if i == len(k.points) {
+		i = 0
+	}
+	return k.points[i].addr, nil
+}
+
+// Each calls the given function for each server.
+func (k *Ketama) Each(fn func(net.Addr) error) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, a := range k.addrs {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ketamaPrefix returns the prefix of the point keys of a server. As in
+// libmemcached, the port is omitted when it is the default port.
+func ketamaPrefix(server string) string {
+	host, port, err := net.SplitHostPort(server)
+	switch {
+	case err != nil:
+		return server + ":0-"
+	case port == strconv.Itoa(defaultPort):
+		return host + "-"
+	default:
+		return server + "-"
+	}
+}
+
+func ketamaHash(digest [md5.Size]byte, h int) uint32 {
+	return uint32(digest[3+h*4])<<24 |
+		uint32(digest[2+h*4])<<16 |
+		uint32(digest[1+h*4])<<8 |
+		uint32(digest[h*4])
+}
+
+func weight(s Server) int {
+	if s.Weight <= 0 {
+		return 1
+	}
+	return s.Weight
+}
+
+func resolveAddr(server string) (net.Addr, error) {
+	if strings.Contains(server, "/") {
+		addr, err := net.ResolveUnixAddr("unix", server)
+		if err != nil {
+			return nil, fmt.Errorf("memcache: invalid server %q: %w", server, err)
+		}
+		return addr, nil
+	}
+
+	addr, err := net.ResolveTCPAddr("tcp", server)
+	if err != nil {
+		return nil, fmt.Errorf("memcache: invalid server %q: %w", server, err)
+	}
+	return addr, nil
+}
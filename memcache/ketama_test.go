@@ -0,0 +1,201 @@
+package memcache_test
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	gomemcache "github.com/bradfitz/gomemcache/memcache"
+	"github.com/hamba/cache/v2/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKetama_PickServer(t *testing.T) {
+	k, err := memcache.NewKetama(
+		memcache.Server{Addr: "127.0.0.1:11211"},
+		memcache.Server{Addr: "127.0.0.2:11211"},
+		memcache.Server{Addr: "127.0.0.3:11211"},
+	)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 30000; i++ {
+		addr, err := k.PickServer("key" + strconv.Itoa(i))
+		require.NoError(t, err)
+		counts[addr.String()]++
+	}
+
+	require.Len(t, counts, 3)
+	for _, n := range counts {
+		assert.InDelta(t, 10000, n, 1500)
+	}
+}
+
+// The known answers were computed with libmemcached's weighted ketama
+// continuum and dispatch (update_continuum and dispatch_host, MD5 hash)
+// compiled as C, so the float arithmetic matches. Each digit is the index
+// of the server picked for the key "key:<position>".
+func TestKetama_PickServerKnownAnswers(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []memcache.Server
+		want    string
+	}{
+		{
+			name: "equal weights",
+			servers: []memcache.Server{
+				{Addr: "127.0.0.1:11211"},
+				{Addr: "127.0.0.2:11211"},
+				{Addr: "127.0.0.3:11211"},
+			},
+			want: "0201220210122202002211112020200111020111122211121222012011202201011002101100202101222011120000111102",
+		},
+		{
+			name: "weighted",
+			servers: []memcache.Server{
+				{Addr: "10.0.1.1:11211", Weight: 600},
+				{Addr: "10.0.1.2:11211", Weight: 300},
+				{Addr: "10.0.1.3:11211", Weight: 200},
+				{Addr: "10.0.1.4:11211", Weight: 350},
+				{Addr: "10.0.1.5:11211", Weight: 1000},
+				{Addr: "10.0.1.6:11211", Weight: 800},
+				{Addr: "10.0.1.7:11211", Weight: 950},
+				{Addr: "10.0.1.8:11211", Weight: 100},
+			},
+			want: "5753654203545640176552406700445666464364416660121331047434556566753424305556625543244460640665146140",
+		},
+		{
+			name: "non-default ports",
+			servers: []memcache.Server{
+				{Addr: "10.0.1.1:11211", Weight: 1},
+				{Addr: "10.0.1.2:11212", Weight: 2},
+				{Addr: "10.0.1.3:11213", Weight: 1},
+			},
+			want: "0012221020112201010112202100100112111121212100110112211110110211211110211100102212112200020201011222",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			k, err := memcache.NewKetama(test.servers...)
+			require.NoError(t, err)
+
+			for i, c := range test.want {
+				addr, err := k.PickServer("key:" + strconv.Itoa(i))
+				require.NoError(t, err)
+
+				want := test.servers[c-'0'].Addr
+				assert.Equal(t, want, addr.String(), "key:%d", i)
+			}
+		})
+	}
+}
+
+func TestKetama_PickServerIsStable(t *testing.T) {
+	servers := []memcache.Server{
+		{Addr: "127.0.0.1:11211"},
+		{Addr: "127.0.0.2:11211"},
+	}
+	k1, err := memcache.NewKetama(servers...)
+	require.NoError(t, err)
+	k2, err := memcache.NewKetama(servers[1], servers[0])
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		addr1, err := k1.PickServer(key)
+		require.NoError(t, err)
+		addr2, err := k2.PickServer(key)
+		require.NoError(t, err)
+
+		assert.Equal(t, addr1.String(), addr2.String())
+	}
+}
+
+func TestKetama_PickServerWithWeights(t *testing.T) {
+	k, err := memcache.NewKetama(
+		memcache.Server{Addr: "127.0.0.1:11211", Weight: 3},
+		memcache.Server{Addr: "127.0.0.2:11211", Weight: 1},
+	)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 40000; i++ {
+		addr, err := k.PickServer("key" + strconv.Itoa(i))
+		require.NoError(t, err)
+		counts[addr.String()]++
+	}
+
+	assert.InDelta(t, 30000, counts["127.0.0.1:11211"], 3000)
+	assert.InDelta(t, 10000, counts["127.0.0.2:11211"], 3000)
+}
+
+func TestKetama_SetServersMovesFewKeys(t *testing.T) {
+	k, err := memcache.NewKetama(
+		memcache.Server{Addr: "127.0.0.1:11211"},
+		memcache.Server{Addr: "127.0.0.2:11211"},
+		memcache.Server{Addr: "127.0.0.3:11211"},
+	)
+	require.NoError(t, err)
+
+	before := map[string]string{}
+	for i := 0; i < 10000; i++ {
+		key := "key" + strconv.Itoa(i)
+		addr, err := k.PickServer(key)
+		require.NoError(t, err)
+		before[key] = addr.String()
+	}
+
+	err = k.SetServers(
+		memcache.Server{Addr: "127.0.0.1:11211"},
+		memcache.Server{Addr: "127.0.0.2:11211"},
+		memcache.Server{Addr: "127.0.0.3:11211"},
+		memcache.Server{Addr: "127.0.0.4:11211"},
+	)
+	require.NoError(t, err)
+
+	moved := 0
+	for key, was := range before {
+		addr, err := k.PickServer(key)
+		require.NoError(t, err)
+		if addr.String() != was {
+			assert.Equal(t, "127.0.0.4:11211", addr.String())
+			moved++
+		}
+	}
+	assert.InDelta(t, 2500, moved, 700)
+}
+
+func TestKetama_NoServers(t *testing.T) {
+	k, err := memcache.NewKetama()
+	require.NoError(t, err)
+
+	_, err = k.PickServer("test")
+
+	assert.ErrorIs(t, err, gomemcache.ErrNoServers)
+}
+
+func TestKetama_InvalidServer(t *testing.T) {
+	_, err := memcache.NewKetama(memcache.Server{Addr: "test"})
+
+	assert.Error(t, err)
+}
+
+func TestKetama_Each(t *testing.T) {
+	k, err := memcache.NewKetama(
+		memcache.Server{Addr: "127.0.0.1:11211"},
+		memcache.Server{Addr: "127.0.0.2:11211"},
+	)
+	require.NoError(t, err)
+
+	var got []string
+	err = k.Each(func(addr net.Addr) error {
+		got = append(got, addr.String())
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"127.0.0.1:11211", "127.0.0.2:11211"}, got)
+}
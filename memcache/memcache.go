@@ -2,10 +2,13 @@
 package memcache
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
@@ -13,6 +16,14 @@ import (
 	"github.com/hamba/cache/v2/internal/decoder"
 )
 
+// multiConcurrency is the maximum number of concurrent requests of a batch.
+const multiConcurrency = 10
+
+// ErrNotSupported is returned if an operation is not supported by the protocol.
+var ErrNotSupported = errors.New("memcache: operation not supported")
+
+var errInvalidToken = errors.New("memcache: invalid cas token")
+
 // OptsFunc represents an configuration function for Memcache.
 type OptsFunc func(*memcache.Client)
 
@@ -30,24 +41,82 @@ func WithTimeout(timeout time.Duration) OptsFunc {
 	}
 }
 
+// client is a memcache protocol client.
+type client interface {
+	Get(key string) (*memcache.Item, error)
+	GetMulti(keys []string) (map[string]*memcache.Item, error)
+	Set(item *memcache.Item) error
+	Add(item *memcache.Item) error
+	Replace(item *memcache.Item) error
+	Delete(key string) error
+	Touch(key string, seconds int32) error
+	Increment(key string, delta uint64) (uint64, error)
+	Decrement(key string, delta uint64) (uint64, error)
+	Ping() error
+	FlushAll() error
+}
+
+// casClient is a client that returns the cas value separately from the item.
+type casClient interface {
+	gets(key string) (*memcache.Item, uint64, error)
+	cas(item *memcache.Item, cas uint64) error
+	decrCAS(key string, delta, cas uint64) (uint64, error)
+	deleteCAS(key string, cas uint64) error
+}
+
+// textCASClient is a client that keeps the cas value in the item.
+type textCASClient interface {
+	CompareAndSwap(item *memcache.Item) error
+}
+
+// idleCloser is a client that can close its idle connections.
+type idleCloser interface {
+	closeIdle() error
+}
+
+// gatClient is a client supporting get and touch.
+type gatClient interface {
+	getAndTouch(key string, seconds int32) (*memcache.Item, error)
+}
+
 // Memcache is a memcache adapter.
 type Memcache struct {
-	client *memcache.Client
+	client client
+
+	// meta is used for the operations the text protocol lacks.
+	meta *Meta
 
 	enc func(v interface{}) ([]byte, error)
 	dec cache.Decoder
 }
 
 // New create a new Memcache instance.
+//
+// Multiple servers can be given as a comma separated list.
 func New(uri string, opts ...OptsFunc) *Memcache {
-	c := memcache.New(uri)
+	// As with memcache.New, invalid servers fail when used.
+	ss := &memcache.ServerList{}
+	_ = ss.SetServers(strings.Split(uri, ",")...)
+
+	return newMemcache(ss, opts)
+}
 
+// NewWithSelector creates a new Memcache instance using the given server selector.
+//
+// The selector can be used to update the servers at runtime.
+func NewWithSelector(ss memcache.ServerSelector, opts ...OptsFunc) *Memcache {
+	return newMemcache(ss, opts)
+}
+
+func newMemcache(ss memcache.ServerSelector, opts []OptsFunc) *Memcache {
+	c := memcache.NewFromSelector(ss)
 	for _, opt := range opts {
 		opt(c)
 	}
 
 	return &Memcache{
 		client: c,
+		meta:   NewMetaWithSelector(ss, MetaConfig{}, opts...),
 		enc:    memcacheEncoder,
 		dec:    decoder.StringDecoder{},
 	}
@@ -145,6 +214,222 @@ func (c Memcache) Delete(_ context.Context, key string) error {
 	return c.client.Delete(key)
 }
 
+// DeleteIfEqual deletes the item with the given key, but only if its value
+// is equal to value. ErrNotStored is returned if the value is not equal,
+// and ErrCacheMiss if the key does not exist.
+//
+// The text protocol uses the meta protocol, requiring memcached 1.6 or later.
+func (c Memcache) DeleteIfEqual(ctx context.Context, key string, value interface{}) error {
+	cc, ok := c.client.(casClient)
+	if !ok {
+		return c.meta.DeleteIfEqual(ctx, key, value)
+	}
+
+	v, err := c.enc(value)
+	if err != nil {
+		return err
+	}
+
+	item, cas, err := cc.gets(key)
+	switch {
+	case errors.Is(err, memcache.ErrCacheMiss):
+		return cache.ErrCacheMiss
+	case err != nil:
+		return err
+	case !bytes.Equal(item.Value, v):
+		return cache.ErrNotStored
+	}
+
+	err = cc.deleteCAS(key, cas)
+	switch {
+	case errors.Is(err, memcache.ErrCASConflict):
+		return cache.ErrNotStored
+	case errors.Is(err, memcache.ErrCacheMiss):
+		return cache.ErrCacheMiss
+	}
+	return err
+}
+
+// SetMulti sets the entries in the cache, using concurrent requests.
+func (c Memcache) SetMulti(_ context.Context, entries ...cache.Entry) []error {
+	return concurrently(len(entries), func(i int) error {
+		e := entries[i]
+		v, err := c.enc(e.Value)
+		if err != nil {
+			return err
+		}
+
+		return c.client.Set(&memcache.Item{
+			Key:        e.Key,
+			Value:      v,
+			Expiration: int32(e.Expire.Seconds()),
+		})
+	})
+}
+
+// DeleteMulti deletes the items with the given keys, using concurrent requests.
+func (c Memcache) DeleteMulti(_ context.Context, keys ...string) []error {
+	return concurrently(len(keys), func(i int) error {
+		err := c.client.Delete(keys[i])
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return cache.ErrCacheMiss
+		}
+		return err
+	})
+}
+
+// Gets gets the item for the given key with its compare and swap token.
+func (c Memcache) Gets(_ context.Context, key string) cache.CASItem {
+	var (
+		v     *memcache.Item
+		token cache.CASToken
+		err   error
+	)
+	if cc, ok := c.client.(casClient); ok {
+		var cas uint64
+		v, cas, err = cc.gets(key)
+		token = cas
+	} else {
+		v, err = c.client.Get(key)
+		token = v
+	}
+
+	switch {
+	case errors.Is(err, memcache.ErrCacheMiss):
+		return cache.CASItem{Item: cache.NewItem(c.dec, []byte(nil), cache.ErrCacheMiss)}
+	case err != nil:
+		return cache.CASItem{Item: cache.NewItem(c.dec, []byte(nil), err)}
+	}
+
+	return cache.CASItem{Item: cache.NewItem(c.dec, v.Value, nil), Token: token}
+}
+
+// CompareAndSwap sets the item in the cache, but only if it has not been
+// modified since the token was read.
+func (c Memcache) CompareAndSwap(
+	_ context.Context,
+	key string,
+	value interface{},
+	token cache.CASToken,
+	expire time.Duration,
+) error {
+	v, err := c.enc(value)
+	if err != nil {
+		return err
+	}
+
+	item := &memcache.Item{
+		Key:        key,
+		Value:      v,
+		Expiration: int32(expire.Seconds()),
+	}
+
+	cc, isCAS := c.client.(casClient)
+	tc, isText := c.client.(textCASClient)
+	switch t := token.(type) {
+	case uint64:
+		if !isCAS {
+			return errInvalidToken
+		}
+		err = cc.cas(item, t)
+	case *memcache.Item:
+		if !isText || t.Key != key {
+			return errInvalidToken
+		}
+		// The cas value is unexported, so the read item is updated.
+		read := *t
+		read.Value, read.Expiration = item.Value, item.Expiration
+		err = tc.CompareAndSwap(&read)
+	default:
+		return errInvalidToken
+	}
+
+	switch {
+	case errors.Is(err, memcache.ErrCASConflict):
+		return cache.ErrCASConflict
+	case errors.Is(err, memcache.ErrCacheMiss):
+		return cache.ErrCacheMiss
+	}
+	return err
+}
+
+// Touch updates the expiration of the item with the given key.
+func (c Memcache) Touch(_ context.Context, key string, expire time.Duration) error {
+	err := c.client.Touch(key, int32(expire.Seconds()))
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return cache.ErrCacheMiss
+	}
+	return err
+}
+
+// GetAndTouch gets the item for the given key and updates its expiration.
+//
+// The text protocol uses the meta protocol, requiring memcached 1.6 or later.
+func (c Memcache) GetAndTouch(ctx context.Context, key string, expire time.Duration) cache.Item {
+	gc, ok := c.client.(gatClient)
+	if !ok {
+		return c.meta.GetAndTouch(ctx, key, expire)
+	}
+
+	b := []byte(nil)
+	v, err := gc.getAndTouch(key, int32(expire.Seconds()))
+	switch {
+	case errors.Is(err, memcache.ErrCacheMiss):
+		err = cache.ErrCacheMiss
+	case err == nil:
+		b = v.Value
+	}
+
+	return cache.NewItem(c.dec, b, err)
+}
+
+// TTL returns the remaining lifetime of the item with the given key.
+//
+// The text protocol uses the meta protocol, requiring memcached 1.6 or later.
+// ErrNotSupported is returned when using the binary protocol.
+func (c Memcache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.meta == nil {
+		return 0, ErrNotSupported
+	}
+	return c.meta.TTL(ctx, key)
+}
+
+// Ping checks that each server responds to a version request.
+func (c Memcache) Ping(_ context.Context) error {
+	return c.client.Ping()
+}
+
+// Flush removes all items from each server.
+func (c Memcache) Flush(_ context.Context) error {
+	return c.client.FlushAll()
+}
+
+// Close closes the idle connections.
+//
+// The text protocol client cannot close its idle connections, so only
+// the connections used for meta protocol operations are closed.
+func (c Memcache) Close() error {
+	var err error
+	if ic, ok := c.client.(idleCloser); ok {
+		err = ic.closeIdle()
+	}
+	if c.meta != nil {
+		if cerr := c.meta.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Scan calls fn with each key matching the pattern. It is not
+// supported by the binary protocol.
+func (c Memcache) Scan(ctx context.Context, pattern string, opts cache.ScanOptions, fn func(key string) error) error {
+	if c.meta == nil {
+		return ErrNotSupported
+	}
+	return c.meta.Scan(ctx, pattern, opts, fn)
+}
+
 // Inc increments a key by the value.
 func (c Memcache) Inc(_ context.Context, key string, value uint64) (int64, error) {
 	v, err := c.client.Increment(key, value)
@@ -157,6 +442,76 @@ func (c Memcache) Dec(_ context.Context, key string, value uint64) (int64, error
 	return int64(v), err
 }
 
+// IncWithOptions adds the delta to the counter with the given key,
+// returning the new value. See cache.CounterCache for details.
+// The expiration is rounded up to whole seconds.
+//
+// The text protocol uses the meta protocol when rejecting underflows,
+// requiring memcached 1.6 or later.
+func (c Memcache) IncWithOptions(
+	ctx context.Context,
+	key string,
+	delta int64,
+	opts cache.CounterOptions,
+) (int64, error) {
+	if _, ok := c.client.(casClient); !ok && opts.Underflow == cache.UnderflowReject {
+		return incWithOptions(ctx, c.meta, key, delta, opts)
+	}
+	return incWithOptions(ctx, c, key, delta, opts)
+}
+
+// IncFloat increments a key by the floating-point value.
+//
+// The item is updated using compare and swap, keeping its remaining lifetime.
+// The binary protocol cannot read the remaining lifetime, so the updated
+// item no longer expires.
+func (c Memcache) IncFloat(ctx context.Context, key string, value float64) (float64, error) {
+	return incFloat(ctx, c, key, value)
+}
+
+// decCAS decrements a key by the value, but only if it has not been
+// modified since the token was read.
+func (c Memcache) decCAS(key string, value uint64, token cache.CASToken) (int64, error) {
+	cc, ok := c.client.(casClient)
+	cas, isCAS := token.(uint64)
+	if !ok || !isCAS {
+		return 0, errInvalidToken
+	}
+
+	v, err := cc.decrCAS(key, value, cas)
+	switch {
+	case errors.Is(err, memcache.ErrCASConflict):
+		return 0, cache.ErrCASConflict
+	case errors.Is(err, memcache.ErrCacheMiss):
+		return 0, cache.ErrCacheMiss
+	}
+	return int64(v), err
+}
+
+// concurrently calls fn for each index, with at most multiConcurrency
+// calls at a time, returning the errors in order.
+func concurrently(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, multiConcurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
+}
+
 func memcacheEncoder(v interface{}) ([]byte, error) {
 	switch val := v.(type) {
 	case bool:
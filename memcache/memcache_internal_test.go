@@ -30,7 +30,7 @@ func TestWithTimeout(t *testing.T) {
 func TestNewMemcache(t *testing.T) {
 	c := New("test", WithIdleConns(12))
 
-	assert.Equal(t, 12, c.client.MaxIdleConns)
+	assert.Equal(t, 12, c.client.(*memcache.Client).MaxIdleConns)
 }
 
 func TestEncoderError(t *testing.T) {
@@ -103,3 +103,62 @@ func TestByteEncode(t *testing.T) {
 		})
 	}
 }
+
+func TestKetamaPrefix(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+	}{
+		{server: "10.0.1.1:11211", want: "10.0.1.1-"},
+		{server: "10.0.1.1:11212", want: "10.0.1.1:11212-"},
+		{server: "cache.example.com:11211", want: "cache.example.com-"},
+		{server: "/tmp/memcached.sock", want: "/tmp/memcached.sock:0-"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.server, func(t *testing.T) {
+			got := ketamaPrefix(test.server)
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestRoundUpSeconds(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want time.Duration
+	}{
+		{
+			name: "no expiration",
+			d:    0,
+			want: 0,
+		},
+		{
+			name: "sub-second",
+			d:    100 * time.Millisecond,
+			want: time.Second,
+		},
+		{
+			name: "whole seconds",
+			d:    2 * time.Second,
+			want: 2 * time.Second,
+		},
+		{
+			name: "fractional seconds",
+			d:    1500 * time.Millisecond,
+			want: 2 * time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			got := roundUpSeconds(test.d)
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/cachetest"
+	"github.com/hamba/cache/v2/lock"
 	"github.com/hamba/cache/v2/memcache"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -91,3 +94,315 @@ func TestMemcacheCache(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, int64(0), i)
 }
+
+func TestMemcacheCache_WithSelector(t *testing.T) {
+	if skipMemcache {
+		t.Skipf("skipping test; no running server at %s", testMemcachedServer)
+	}
+
+	ctx := context.Background()
+
+	k, err := memcache.NewKetama(memcache.Server{Addr: testMemcachedServer})
+	require.NoError(t, err)
+	c := memcache.NewWithSelector(k)
+
+	err = c.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	str, err := c.Get(ctx, "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+}
+
+func TestMemcacheCache_CompareAndSwap(t *testing.T) {
+	if skipMemcache {
+		t.Skipf("skipping test; no running server at %s", testMemcachedServer)
+	}
+
+	c := memcache.New(testMemcachedServer)
+
+	testCASCache(t, c)
+}
+
+func TestMemcacheCache_Touch(t *testing.T) {
+	if skipMemcache {
+		t.Skipf("skipping test; no running server at %s", testMemcachedServer)
+	}
+
+	c := memcache.New(testMemcachedServer)
+
+	testTouchCache(t, c)
+
+	err := c.Set(context.Background(), "test", "foobar", time.Minute)
+	require.NoError(t, err)
+
+	ttl, err := c.TTL(context.Background(), "test")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+}
+
+func TestMemcacheCache_Lock(t *testing.T) {
+	if skipMemcache {
+		t.Skipf("skipping test; no running server at %s", testMemcachedServer)
+	}
+
+	c := memcache.New(testMemcachedServer)
+
+	testLockCache(t, c)
+}
+
+func TestMemcacheCache_Counter(t *testing.T) {
+	if skipMemcache {
+		t.Skipf("skipping test; no running server at %s", testMemcachedServer)
+	}
+
+	c := memcache.New(testMemcachedServer)
+
+	cachetest.Counter(t, c)
+}
+
+func TestMemcacheCache_Float(t *testing.T) {
+	if skipMemcache {
+		t.Skipf("skipping test; no running server at %s", testMemcachedServer)
+	}
+
+	c := memcache.New(testMemcachedServer)
+
+	cachetest.Float(t, c)
+}
+
+func TestMemcacheCache_TTLUsesMeta(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	m, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+	err = m.Set(ctx, "test", "foobar", time.Minute)
+	require.NoError(t, err)
+
+	c := memcache.New(addr)
+
+	ttl, err := c.TTL(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	str, err := c.GetAndTouch(ctx, "test", time.Hour).String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+
+	ttl, err = c.TTL(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+}
+
+func TestMemcacheCache_ScanUsesMeta(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	m, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+	err = m.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	c := memcache.New(addr)
+
+	assert.Implements(t, (*cache.Scanner)(nil), c)
+
+	var keys []string
+	err = c.Scan(ctx, "*", cache.ScanOptions{}, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test"}, keys)
+}
+
+func TestMemcacheCache_Lifecycle(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	m, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+	err = m.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	c := memcache.New(addr)
+
+	err = c.Ping(ctx)
+	require.NoError(t, err)
+
+	err = c.Flush(ctx)
+	require.NoError(t, err)
+	assert.ErrorIs(t, m.Get(ctx, "test").Err, cache.ErrCacheMiss)
+
+	err = c.Close()
+	assert.NoError(t, err)
+}
+
+func TestMemcacheCache_StatsUsesMeta(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c := memcache.New(addr)
+
+	assert.Implements(t, (*cache.StatsCache)(nil), c)
+
+	stats, err := c.Stats(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, stats.Uptime)
+	assert.Equal(t, 1, stats.Pool.TotalConns)
+}
+
+func TestMemcacheCache_DeleteIfEqualUsesMeta(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	m, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+	err = m.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	c := memcache.New(addr)
+
+	err = c.DeleteIfEqual(ctx, "test", "bar")
+	assert.ErrorIs(t, err, cache.ErrNotStored)
+
+	err = c.DeleteIfEqual(ctx, "test", "foobar")
+	require.NoError(t, err)
+
+	err = c.DeleteIfEqual(ctx, "test", "foobar")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func testTouchCache(t *testing.T, c cache.TouchCache) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	err := c.Set(ctx, "test", "foobar", time.Minute)
+	require.NoError(t, err)
+
+	err = c.Touch(ctx, "test", time.Hour)
+	require.NoError(t, err)
+
+	err = c.Touch(ctx, "_", time.Hour)
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	str, err := c.GetAndTouch(ctx, "test", time.Hour).String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+
+	_, err = c.GetAndTouch(ctx, "_", time.Hour).String()
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func testLockCache(t *testing.T, c lock.Cache) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	err := c.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	err = c.DeleteIfEqual(ctx, "test", "bar")
+	assert.ErrorIs(t, err, cache.ErrNotStored)
+
+	err = c.DeleteIfEqual(ctx, "test", "foobar")
+	require.NoError(t, err)
+
+	err = c.DeleteIfEqual(ctx, "test", "foobar")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	l := lock.New(c)
+
+	lease, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+
+	_, err = l.TryLock(ctx, "test")
+	assert.ErrorIs(t, err, lock.ErrNotAcquired)
+
+	err = lease.Renew(ctx)
+	require.NoError(t, err)
+
+	err = lease.Release(ctx)
+	require.NoError(t, err)
+
+	next, err := l.TryLock(ctx, "test")
+	require.NoError(t, err)
+	assert.Greater(t, next.Fence(), lease.Fence())
+
+	err = next.Release(ctx)
+	require.NoError(t, err)
+}
+
+func TestMemcacheCache_Multi(t *testing.T) {
+	if skipMemcache {
+		t.Skipf("skipping test; no running server at %s", testMemcachedServer)
+	}
+
+	c := memcache.New(testMemcachedServer)
+
+	testMultiCache(t, c)
+}
+
+func testMultiCache(t *testing.T, c cache.MultiCache) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	errs := c.SetMulti(ctx,
+		cache.Entry{Key: "multi1", Value: "foo"},
+		cache.Entry{Key: "multi 2", Value: "bar"},
+		cache.Entry{Key: "multi3", Value: 3, Expire: time.Minute},
+	)
+	require.Len(t, errs, 3)
+	assert.NoError(t, errs[0])
+	assert.Error(t, errs[1])
+	assert.NoError(t, errs[2])
+
+	v, err := c.GetMulti(ctx, "multi1", "multi3")
+	require.NoError(t, err)
+	str, err := v[0].String()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", str)
+	i, err := v[1].Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), i)
+
+	errs = c.DeleteMulti(ctx, "multi1", "multi3")
+	assert.Equal(t, []error{nil, nil}, errs)
+
+	v, err = c.GetMulti(ctx, "multi1", "multi3")
+	require.NoError(t, err)
+	assert.ErrorIs(t, v[0].Err, cache.ErrCacheMiss)
+	assert.ErrorIs(t, v[1].Err, cache.ErrCacheMiss)
+}
+
+func testLifecycle(t *testing.T, c interface {
+	cache.Pinger
+	cache.Flusher
+	cache.Closer
+}) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	err := c.Ping(ctx)
+	require.NoError(t, err)
+
+	err = c.Add(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	err = c.Flush(ctx)
+	require.NoError(t, err)
+
+	err = c.Add(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	err = c.Close()
+	require.NoError(t, err)
+
+	err = c.Ping(ctx)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,693 @@
+package memcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/decoder"
+)
+
+// MetaConfig configures a memcache meta protocol client.
+type MetaConfig struct {
+	// TLSConfig enables TLS when set. The server name defaults to the
+	// host of the server address. Server host names are resolved when
+	// the servers are set, so ServerName must be set for certificates
+	// issued to a host name.
+	TLSConfig *tls.Config
+
+	// Dialer is used to connect to the servers. Defaults to a net.Dialer.
+	Dialer DialFunc
+}
+
+// MetaGetOptions configures a meta get.
+type MetaGetOptions struct {
+	// VivifyTTL creates an empty item with the given TTL on a miss. Only the
+	// first client to miss is given the win flag, and should populate the item.
+	VivifyTTL time.Duration
+
+	// RecacheTTL gives the win flag to a single client if the remaining TTL
+	// of the item is less than the given TTL, allowing it to be recached early.
+	RecacheTTL time.Duration
+
+	// TouchTTL updates the TTL of the item.
+	TouchTTL time.Duration
+}
+
+// MetaItem is a cache item with its metadata.
+type MetaItem struct {
+	cache.Item
+
+	// TTL is the remaining lifetime of the item, or cache.NoExpiration
+	// if it does not expire.
+	TTL time.Duration
+
+	// CAS is the compare and swap token of the item.
+	CAS uint64
+
+	// Win is set if this client should populate or recache the item.
+	Win bool
+
+	// WinSent is set if another client was given the win flag.
+	WinSent bool
+
+	// Stale is set if the item has been marked as stale.
+	Stale bool
+}
+
+// Meta is a memcache adapter using the memcache meta protocol.
+type Meta struct {
+	pool *pool
+
+	enc func(v interface{}) ([]byte, error)
+	dec cache.Decoder
+}
+
+// NewMeta creates a new Meta instance.
+//
+// Multiple servers can be given as a comma separated list.
+func NewMeta(uri string, cfg MetaConfig, opts ...OptsFunc) (*Meta, error) {
+	ss := &memcache.ServerList{}
+	if err := ss.SetServers(strings.Split(uri, ",")...); err != nil {
+		return nil, err
+	}
+
+	return NewMetaWithSelector(ss, cfg, opts...), nil
+}
+
+// NewMetaWithSelector creates a new Meta instance using the given server selector.
+func NewMetaWithSelector(ss memcache.ServerSelector, cfg MetaConfig, opts ...OptsFunc) *Meta {
+	p := newPool(ss, cfg.Dialer, cfg.TLSConfig, opts)
+	p.resumable = isMetaResumable
+	p.drain = drainMeta
+
+	return &Meta{
+		pool: p,
+		enc:  memcacheEncoder,
+		dec:  decoder.StringDecoder{},
+	}
+}
+
+// Get gets the item for the given key.
+func (c *Meta) Get(ctx context.Context, key string) cache.Item {
+	return c.GetMeta(ctx, key, MetaGetOptions{}).Item
+}
+
+// GetMeta gets the item for the given key with its metadata.
+func (c *Meta) GetMeta(_ context.Context, key string, opts MetaGetOptions) MetaItem {
+	flags := []string{"v", "t", "c"}
+	if opts.VivifyTTL > 0 {
+		flags = append(flags, "N"+seconds(opts.VivifyTTL))
+	}
+	if opts.RecacheTTL > 0 {
+		flags = append(flags, "R"+seconds(opts.RecacheTTL))
+	}
+	if opts.TouchTTL > 0 {
+		flags = append(flags, "T"+seconds(opts.TouchTTL))
+	}
+
+	resp, err := c.metaGet(key, flags)
+	if err != nil {
+		return MetaItem{Item: cache.NewItem(c.dec, []byte(nil), err)}
+	}
+
+	item := metaItem(c.dec, resp)
+	// A vivified item is empty until it is populated.
+	if opts.VivifyTTL > 0 && resp.status == "VA" && len(resp.value) == 0 {
+		item.Item = cache.NewItem(c.dec, []byte(nil), cache.ErrCacheMiss)
+	}
+	return item
+}
+
+// Touch updates the expiration of the item with the given key.
+func (c *Meta) Touch(_ context.Context, key string, expire time.Duration) error {
+	resp, err := c.metaGet(key, []string{"T" + seconds(expire)})
+	if err != nil {
+		return err
+	}
+	return metaStatusError(resp.status)
+}
+
+// GetAndTouch gets the item for the given key and updates its expiration.
+func (c *Meta) GetAndTouch(_ context.Context, key string, expire time.Duration) cache.Item {
+	resp, err := c.metaGet(key, []string{"v", "T" + seconds(expire)})
+	if err != nil {
+		return cache.NewItem(c.dec, []byte(nil), err)
+	}
+	return metaItem(c.dec, resp).Item
+}
+
+// TTL returns the remaining lifetime of the item with the given key.
+func (c *Meta) TTL(_ context.Context, key string) (time.Duration, error) {
+	resp, err := c.metaGet(key, []string{"t"})
+	if err != nil {
+		return 0, err
+	}
+	if err = metaStatusError(resp.status); err != nil {
+		return 0, err
+	}
+	return metaItem(c.dec, resp).TTL, nil
+}
+
+func (c *Meta) metaGet(key string, flags []string) (metaResponse, error) {
+	var resp metaResponse
+	err := c.pool.withKeyConn(key, func(cn *conn) error {
+		var err error
+		resp, err = roundTripMeta(cn, "mg", key, flags, nil)
+		return err
+	})
+	return resp, err
+}
+
+// GetMulti gets the items for the given keys.
+//
+// The gets are pipelined per server using opaque tokens, with misses
+// suppressed by quiet mode.
+func (c *Meta) GetMulti(_ context.Context, keys ...string) ([]cache.Item, error) {
+	var mu sync.Mutex
+	vals := make(map[string][]byte, len(keys))
+	err := c.pool.withKeysConn(keys, func(cn *conn, keys []string) error {
+		for i, k := range keys {
+			if err := writeMeta(cn, "mg", k, []string{"v", "q", "O" + strconv.Itoa(i)}, nil); err != nil {
+				return err
+			}
+		}
+		if err := writeMeta(cn, "mn", "", nil, nil); err != nil {
+			return err
+		}
+		if err := cn.rw.Flush(); err != nil {
+			return err
+		}
+
+		for {
+			resp, err := readMeta(cn)
+			if err != nil {
+				return err
+			}
+			if resp.status == "MN" {
+				return nil
+			}
+
+			o, _ := resp.flag('O')
+			i, err := strconv.Atoi(o)
+			if err != nil || i < 0 || i >= len(keys) {
+				return fmt.Errorf("memcache: invalid opaque %q", o)
+			}
+			mu.Lock()
+			vals[keys[i]] = resp.value
+			mu.Unlock()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]cache.Item, 0, len(keys))
+	for _, k := range keys {
+		v, ok := vals[k]
+		if !ok {
+			items = append(items, cache.NewItem(c.dec, []byte(nil), cache.ErrCacheMiss))
+			continue
+		}
+		items = append(items, cache.NewItem(c.dec, v, nil))
+	}
+	return items, nil
+}
+
+// SetMulti sets the entries in the cache.
+//
+// The sets are pipelined per server in quiet mode, so only failures are
+// returned by the server.
+func (c *Meta) SetMulti(_ context.Context, entries ...cache.Entry) []error {
+	keys := make([]string, len(entries))
+	vals := make([][]byte, len(entries))
+	errs := make([]error, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+		vals[i], errs[i] = c.enc(e.Value)
+	}
+
+	c.pool.withKeyIndexesConn(keys, errs, func(cn *conn, idxs []int) error {
+		for _, i := range idxs {
+			flags := []string{strconv.Itoa(len(vals[i])), "T" + seconds(entries[i].Expire), "q", "O" + strconv.Itoa(i)}
+			if err := writeMeta(cn, "ms", keys[i], flags, vals[i]); err != nil {
+				return err
+			}
+		}
+		return readQuiet(cn, errs)
+	})
+	return errs
+}
+
+// DeleteMulti deletes the items with the given keys.
+//
+// The deletes are pipelined per server in quiet mode, so only failures are
+// returned by the server.
+func (c *Meta) DeleteMulti(_ context.Context, keys ...string) []error {
+	errs := make([]error, len(keys))
+	c.pool.withKeyIndexesConn(keys, errs, func(cn *conn, idxs []int) error {
+		for _, i := range idxs {
+			if err := writeMeta(cn, "md", keys[i], []string{"q", "O" + strconv.Itoa(i)}, nil); err != nil {
+				return err
+			}
+		}
+		return readQuiet(cn, errs)
+	})
+	return errs
+}
+
+// readQuiet writes a noop, and sets the errors of the quiet commands
+// written before it using their opaque tokens.
+func readQuiet(cn *conn, errs []error) error {
+	if err := writeMeta(cn, "mn", "", nil, nil); err != nil {
+		return err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		resp, err := readMeta(cn)
+		if err != nil {
+			return err
+		}
+		if resp.status == "MN" {
+			return nil
+		}
+
+		o, _ := resp.flag('O')
+		i, err := strconv.Atoi(o)
+		if err != nil || i < 0 || i >= len(errs) {
+			return fmt.Errorf("memcache: invalid opaque %q", o)
+		}
+		errs[i] = metaStatusError(resp.status)
+	}
+}
+
+// Set sets the item in the cache.
+func (c *Meta) Set(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	return c.store(key, value, expire, "MS")
+}
+
+// Add sets the item in the cache, but only if the key does not already exist.
+func (c *Meta) Add(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	return c.store(key, value, expire, "ME")
+}
+
+// Replace sets the item in the cache, but only if the key already exists.
+func (c *Meta) Replace(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	return c.store(key, value, expire, "MR")
+}
+
+// SetNoReply sets the item in the cache without waiting for a reply.
+//
+// Any error returned by the server is discarded.
+func (c *Meta) SetNoReply(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	v, err := c.enc(value)
+	if err != nil {
+		return err
+	}
+
+	return c.pool.withKeyConn(key, func(cn *conn) error {
+		flags := []string{strconv.Itoa(len(v)), "T" + seconds(expire), "q"}
+		return writeNoReply(cn, "ms", key, flags, v)
+	})
+}
+
+// Gets gets the item for the given key with its compare and swap token.
+func (c *Meta) Gets(ctx context.Context, key string) cache.CASItem {
+	item := c.GetMeta(ctx, key, MetaGetOptions{})
+	if item.Err != nil {
+		return cache.CASItem{Item: item.Item}
+	}
+	return cache.CASItem{Item: item.Item, Token: item.CAS}
+}
+
+// CompareAndSwap sets the item in the cache, but only if it has not been
+// modified since the token was read.
+func (c *Meta) CompareAndSwap(
+	_ context.Context,
+	key string,
+	value interface{},
+	token cache.CASToken,
+	expire time.Duration,
+) error {
+	cas, ok := token.(uint64)
+	if !ok {
+		return errInvalidToken
+	}
+	return c.store(key, value, expire, "MS", "C"+strconv.FormatUint(cas, 10))
+}
+
+func (c *Meta) store(key string, value interface{}, expire time.Duration, extra ...string) error {
+	v, err := c.enc(value)
+	if err != nil {
+		return err
+	}
+
+	return c.pool.withKeyConn(key, func(cn *conn) error {
+		flags := append([]string{strconv.Itoa(len(v)), "T" + seconds(expire)}, extra...)
+		resp, err := roundTripMeta(cn, "ms", key, flags, v)
+		if err != nil {
+			return err
+		}
+		return metaStatusError(resp.status)
+	})
+}
+
+// IncWithOptions adds the delta to the counter with the given key,
+// returning the new value. See cache.CounterCache for details.
+// The expiration is rounded up to whole seconds.
+func (c *Meta) IncWithOptions(ctx context.Context, key string, delta int64, opts cache.CounterOptions) (int64, error) {
+	return incWithOptions(ctx, c, key, delta, opts)
+}
+
+// IncFloat increments a key by the floating-point value.
+//
+// The item is updated using compare and swap, keeping its remaining lifetime.
+func (c *Meta) IncFloat(ctx context.Context, key string, value float64) (float64, error) {
+	return incFloat(ctx, c, key, value)
+}
+
+// Delete deletes the item with the given key.
+func (c *Meta) Delete(_ context.Context, key string) error {
+	return c.pool.withKeyConn(key, func(cn *conn) error {
+		resp, err := roundTripMeta(cn, "md", key, nil, nil)
+		if err != nil {
+			return err
+		}
+		return metaStatusError(resp.status)
+	})
+}
+
+// DeleteIfEqual deletes the item with the given key, but only if its value
+// is equal to value. ErrNotStored is returned if the value is not equal,
+// and ErrCacheMiss if the key does not exist.
+func (c *Meta) DeleteIfEqual(ctx context.Context, key string, value interface{}) error {
+	v, err := c.enc(value)
+	if err != nil {
+		return err
+	}
+
+	item := c.GetMeta(ctx, key, MetaGetOptions{})
+	if item.Err != nil {
+		return item.Err
+	}
+	if b, _ := item.Bytes(); !bytes.Equal(b, v) {
+		return cache.ErrNotStored
+	}
+
+	return c.pool.withKeyConn(key, func(cn *conn) error {
+		resp, err := roundTripMeta(cn, "md", key, []string{"C" + strconv.FormatUint(item.CAS, 10)}, nil)
+		if err != nil {
+			return err
+		}
+		if err = metaStatusError(resp.status); errors.Is(err, cache.ErrCASConflict) {
+			return cache.ErrNotStored
+		}
+		return err
+	})
+}
+
+// DeleteNoReply deletes the item with the given key without waiting for a reply.
+//
+// Any error returned by the server is discarded.
+func (c *Meta) DeleteNoReply(_ context.Context, key string) error {
+	return c.pool.withKeyConn(key, func(cn *conn) error {
+		return writeNoReply(cn, "md", key, []string{"q"}, nil)
+	})
+}
+
+// Ping checks that each server responds to a version request.
+func (c *Meta) Ping(_ context.Context) error {
+	return c.textCommand("version", "VERSION ")
+}
+
+// Flush removes all items from each server.
+func (c *Meta) Flush(_ context.Context) error {
+	return c.textCommand("flush_all", "OK")
+}
+
+// Close closes the idle connections.
+func (c *Meta) Close() error {
+	return c.pool.closeIdle()
+}
+
+// textCommand sends the text protocol command to each server,
+// expecting a response starting with the prefix.
+func (c *Meta) textCommand(cmd, prefix string) error {
+	return c.pool.each(func(cn *conn) error {
+		if _, err := cn.rw.WriteString(cmd + "\r\n"); err != nil {
+			return err
+		}
+		if err := cn.rw.Flush(); err != nil {
+			return err
+		}
+
+		line, err := cn.rw.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(line, prefix):
+			return nil
+		case strings.HasPrefix(line, "ERROR"), strings.HasPrefix(line, "CLIENT_ERROR"),
+			strings.HasPrefix(line, "SERVER_ERROR"):
+			return &ServerError{Message: strings.TrimSpace(line)}
+		default:
+			return fmt.Errorf("memcache: unexpected response %q", line)
+		}
+	})
+}
+
+// Inc increments a key by the value.
+func (c *Meta) Inc(_ context.Context, key string, value uint64) (int64, error) {
+	return c.arithmetic(key, value, "MI")
+}
+
+// Dec decrements a key by the value.
+func (c *Meta) Dec(_ context.Context, key string, value uint64) (int64, error) {
+	return c.arithmetic(key, value, "MD")
+}
+
+// decCAS decrements a key by the value, but only if it has not been
+// modified since the token was read.
+func (c *Meta) decCAS(key string, value uint64, token cache.CASToken) (int64, error) {
+	cas, ok := token.(uint64)
+	if !ok {
+		return 0, errInvalidToken
+	}
+	return c.arithmetic(key, value, "MD", "C"+strconv.FormatUint(cas, 10))
+}
+
+func (c *Meta) arithmetic(key string, value uint64, mode string, extra ...string) (int64, error) {
+	var v int64
+	err := c.pool.withKeyConn(key, func(cn *conn) error {
+		flags := append([]string{"D" + strconv.FormatUint(value, 10), mode, "v"}, extra...)
+		resp, err := roundTripMeta(cn, "ma", key, flags, nil)
+		if err != nil {
+			return err
+		}
+		if err = metaStatusError(resp.status); err != nil {
+			return err
+		}
+
+		n, err := strconv.ParseUint(string(resp.value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("memcache: invalid counter value: %w", err)
+		}
+		v = int64(n)
+		return nil
+	})
+	return v, err
+}
+
+// metaResponse is a meta protocol response.
+type metaResponse struct {
+	status string
+	flags  []string
+	value  []byte
+}
+
+// flag returns the token of the given return flag.
+func (r metaResponse) flag(f byte) (string, bool) {
+	for _, fl := range r.flags {
+		if fl[0] == f {
+			return fl[1:], true
+		}
+	}
+	return "", false
+}
+
+func metaItem(dec cache.Decoder, resp metaResponse) MetaItem {
+	if resp.status == "EN" {
+		return MetaItem{Item: cache.NewItem(dec, []byte(nil), cache.ErrCacheMiss)}
+	}
+
+	item := MetaItem{Item: cache.NewItem(dec, resp.value, nil)}
+	if t, ok := resp.flag('t'); ok {
+		ttl, _ := strconv.ParseInt(t, 10, 64)
+		item.TTL = time.Duration(ttl) * time.Second
+		if ttl < 0 {
+			item.TTL = cache.NoExpiration
+		}
+	}
+	if cas, ok := resp.flag('c'); ok {
+		item.CAS, _ = strconv.ParseUint(cas, 10, 64)
+	}
+	_, item.Win = resp.flag('W')
+	_, item.WinSent = resp.flag('Z')
+	_, item.Stale = resp.flag('X')
+
+	return item
+}
+
+func writeMeta(cn *conn, cmd, key string, flags []string, value []byte) error {
+	parts := make([]string, 0, len(flags)+2)
+	parts = append(parts, cmd)
+	if key != "" {
+		parts = append(parts, key)
+	}
+	parts = append(parts, flags...)
+
+	if _, err := cn.rw.WriteString(strings.Join(parts, " ") + "\r\n"); err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	if _, err := cn.rw.Write(value); err != nil {
+		return err
+	}
+	_, err := cn.rw.WriteString("\r\n")
+	return err
+}
+
+func roundTripMeta(cn *conn, cmd, key string, flags []string, value []byte) (metaResponse, error) {
+	if err := writeMeta(cn, cmd, key, flags, value); err != nil {
+		return metaResponse{}, err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return metaResponse{}, err
+	}
+	return readMeta(cn)
+}
+
+// writeNoReply writes a quiet command followed by a noop, leaving the
+// noop response to be drained before the connection is reused.
+func writeNoReply(cn *conn, cmd, key string, flags []string, value []byte) error {
+	if err := writeMeta(cn, cmd, key, flags, value); err != nil {
+		return err
+	}
+	if err := writeMeta(cn, "mn", "", nil, nil); err != nil {
+		return err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return err
+	}
+
+	cn.pending++
+	return nil
+}
+
+// drainMeta discards the responses of quiet commands up to and
+// including each pending noop.
+func drainMeta(cn *conn) error {
+	for cn.pending > 0 {
+		resp, err := readMeta(cn)
+		var srvErr *ServerError
+		if err != nil && !errors.As(err, &srvErr) {
+			return err
+		}
+		if resp.status == "MN" {
+			cn.pending--
+		}
+	}
+	return nil
+}
+
+func readMeta(cn *conn) (metaResponse, error) {
+	line, err := cn.rw.ReadString('\n')
+	if err != nil {
+		return metaResponse{}, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return metaResponse{}, errors.New("memcache: empty response")
+	}
+
+	resp := metaResponse{status: fields[0], flags: fields[1:]}
+	switch resp.status {
+	case "VA":
+		if len(fields) < 2 {
+			return metaResponse{}, fmt.Errorf("memcache: invalid response %q", line)
+		}
+		size, err := strconv.Atoi(fields[1])
+		if err != nil || size < 0 {
+			return metaResponse{}, fmt.Errorf("memcache: invalid response %q", line)
+		}
+		resp.flags = fields[2:]
+		resp.value = make([]byte, size+2)
+		if _, err = io.ReadFull(cn.rw, resp.value); err != nil {
+			return metaResponse{}, err
+		}
+		resp.value = resp.value[:size]
+	case "HD", "EN", "NF", "NS", "EX", "MN":
+	case "ERROR", "CLIENT_ERROR", "SERVER_ERROR":
+		return metaResponse{}, &ServerError{Message: strings.TrimSpace(line)}
+	default:
+		return metaResponse{}, fmt.Errorf("memcache: unexpected response %q", line)
+	}
+
+	return resp, nil
+}
+
+func metaStatusError(status string) error {
+	switch status {
+	case "HD", "VA":
+		return nil
+	case "EN", "NF":
+		return cache.ErrCacheMiss
+	case "NS":
+		return cache.ErrNotStored
+	case "EX":
+		return cache.ErrCASConflict
+	default:
+		return fmt.Errorf("memcache: unexpected status %q", status)
+	}
+}
+
+// ServerError is returned when a meta protocol server responds with an error.
+type ServerError struct {
+	Message string
+}
+
+// Error returns the error message.
+func (e *ServerError) Error() string {
+	return "memcache: " + e.Message
+}
+
+// isMetaResumable determines if the connection can be reused after the error.
+func isMetaResumable(err error) bool {
+	switch {
+	case errors.Is(err, cache.ErrCacheMiss),
+		errors.Is(err, cache.ErrNotStored),
+		errors.Is(err, cache.ErrCASConflict),
+		errors.Is(err, memcache.ErrMalformedKey):
+		return true
+	}
+	return false
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(d.Seconds()))
+}
@@ -0,0 +1,563 @@
+package memcache_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/cachetest"
+	"github.com/hamba/cache/v2/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaCache(t *testing.T) {
+	if skipMemcache {
+		t.Skipf("skipping test; no running server at %s", testMemcachedServer)
+	}
+
+	c, err := memcache.NewMeta(testMemcachedServer, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	testCache(t, c)
+}
+
+func TestMetaCache_StandIn(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{}, memcache.WithTimeout(time.Second))
+	require.NoError(t, err)
+
+	testCache(t, c)
+}
+
+func TestMetaCache_GetMeta(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "test", "foobar", time.Minute)
+	require.NoError(t, err)
+
+	got := c.GetMeta(ctx, "test", memcache.MetaGetOptions{})
+
+	require.NoError(t, got.Err)
+	str, err := got.String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+	assert.Equal(t, time.Minute, got.TTL)
+	assert.NotZero(t, got.CAS)
+	assert.False(t, got.Win)
+
+	err = c.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	got = c.GetMeta(ctx, "test", memcache.MetaGetOptions{})
+
+	require.NoError(t, got.Err)
+	assert.Equal(t, time.Duration(-1), got.TTL)
+
+	got = c.GetMeta(ctx, "test", memcache.MetaGetOptions{TouchTTL: time.Hour})
+
+	require.NoError(t, got.Err)
+	assert.Equal(t, time.Hour, got.TTL)
+}
+
+func TestMetaCache_GetMetaVivify(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	opts := memcache.MetaGetOptions{VivifyTTL: time.Second}
+
+	got := c.GetMeta(ctx, "test", opts)
+
+	assert.ErrorIs(t, got.Err, cache.ErrCacheMiss)
+	assert.True(t, got.Win)
+	assert.False(t, got.WinSent)
+
+	got = c.GetMeta(ctx, "test", opts)
+
+	assert.ErrorIs(t, got.Err, cache.ErrCacheMiss)
+	assert.False(t, got.Win)
+	assert.True(t, got.WinSent)
+}
+
+func TestMetaCache_GetMetaRecache(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "test", "foobar", 10*time.Second)
+	require.NoError(t, err)
+
+	opts := memcache.MetaGetOptions{RecacheTTL: time.Minute}
+
+	got := c.GetMeta(ctx, "test", opts)
+
+	require.NoError(t, got.Err)
+	assert.True(t, got.Win)
+
+	got = c.GetMeta(ctx, "test", opts)
+
+	require.NoError(t, got.Err)
+	assert.False(t, got.Win)
+	assert.True(t, got.WinSent)
+}
+
+func TestMetaCache_NoReply(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	err = c.SetNoReply(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	str, err := c.Get(ctx, "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+
+	err = c.DeleteNoReply(ctx, "test")
+	require.NoError(t, err)
+	err = c.DeleteNoReply(ctx, "test")
+	require.NoError(t, err)
+
+	_, err = c.Get(ctx, "test").String()
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestMetaCache_ServerError(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	_, err = c.Inc(context.Background(), "test", 1)
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	err = c.Set(context.Background(), "test", "foobar", 0)
+	require.NoError(t, err)
+
+	_, err = c.Inc(context.Background(), "test", 1)
+	var srvErr *memcache.ServerError
+	assert.ErrorAs(t, err, &srvErr)
+}
+
+func TestMetaCache_CompareAndSwap(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	testCASCache(t, c)
+}
+
+func TestMetaCache_Touch(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	testTouchCache(t, c)
+}
+
+func TestMetaCache_Lock(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	testLockCache(t, c)
+}
+
+func TestMetaCache_TTL(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "test", "foobar", time.Minute)
+	require.NoError(t, err)
+	err = c.Set(ctx, "test1", "foobar", 0)
+	require.NoError(t, err)
+
+	ttl, err := c.TTL(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	ttl, err = c.TTL(ctx, "test1")
+	require.NoError(t, err)
+	assert.Equal(t, cache.NoExpiration, ttl)
+
+	_, err = c.TTL(ctx, "_")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestMetaCache_Multi(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	testMultiCache(t, c)
+}
+
+func TestMetaCache_Counter(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	cachetest.Counter(t, c)
+}
+
+func TestMetaCache_Float(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	cachetest.Float(t, c)
+}
+
+func TestMetaCache_Scan(t *testing.T) {
+	ctx := context.Background()
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.Scanner)(nil), c)
+
+	for _, k := range []string{"user:1", "user:2", "user:a%b", "item:1"} {
+		err = c.Set(ctx, k, "foo", 0)
+		require.NoError(t, err)
+	}
+
+	var keys []string
+	err = c.Scan(ctx, "user:*", cache.ScanOptions{BatchSize: 1}, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:1", "user:2", "user:a%b"}, keys)
+
+	err = c.Scan(ctx, "*", cache.ScanOptions{}, func(string) error {
+		return cache.ErrCacheMiss
+	})
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	str, err := c.Get(ctx, "item:1").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", str)
+}
+
+func TestMetaCache_Lifecycle(t *testing.T) {
+	addr := newMetaStandIn(t)
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	testLifecycle(t, c)
+}
+
+func TestMetaCache_Stats(t *testing.T) {
+	addr := newMetaStandIn(t)
+	ctx := context.Background()
+
+	c, err := memcache.NewMeta(addr, memcache.MetaConfig{})
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.StatsCache)(nil), c)
+
+	err = c.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	stats, err := c.Stats(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Items)
+	assert.Equal(t, int64(3), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, time.Minute, stats.Uptime)
+	assert.Equal(t, cache.PoolStats{Hits: 1, Misses: 1, TotalConns: 1, IdleConns: 1}, stats.Pool)
+}
+
+func TestNewMeta_InvalidURI(t *testing.T) {
+	_, err := memcache.NewMeta("test", memcache.MetaConfig{})
+
+	assert.Error(t, err)
+}
+
+// newMetaStandIn starts a minimal in-memory memcached meta protocol server.
+func newMetaStandIn(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &metaStandIn{data: map[string]*metaStandInItem{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+type metaStandInItem struct {
+	value   []byte
+	ttl     int
+	cas     uint64
+	winSent bool
+}
+
+type metaStandIn struct {
+	mu   sync.Mutex
+	cas  uint64
+	data map[string]*metaStandInItem
+}
+
+func (s *metaStandIn) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+
+		var value []byte
+		if fields[0] == "ms" {
+			n, _ := strconv.Atoi(fields[2])
+			value = make([]byte, n+2)
+			if _, err = io.ReadFull(r, value); err != nil {
+				return
+			}
+			value = value[:n]
+		}
+
+		resp := s.handle(fields, value)
+		if resp == "" {
+			continue
+		}
+		_, _ = w.WriteString(resp)
+		if err = w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *metaStandIn) handle(fields []string, value []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flags := map[byte]string{}
+	for _, f := range fields[1:] {
+		flags[f[0]] = f[1:]
+	}
+	_, quiet := flags['q']
+
+	var status, resp string
+	switch fields[0] {
+	case "mn":
+		return "MN\r\n"
+	case "mg":
+		status, resp = s.get(fields[1], flags)
+	case "ms":
+		status = s.set(fields[1], value, flags)
+	case "md":
+		item, ok := s.data[fields[1]]
+		c, hasCAS := flags['C']
+		switch {
+		case !ok:
+			status = "NF"
+		case hasCAS && c != strconv.FormatUint(item.cas, 10):
+			status = "EX"
+		default:
+			delete(s.data, fields[1])
+			status = "HD"
+		}
+	case "ma":
+		status, resp = s.arithmetic(fields[1], flags)
+	case "lru_crawler":
+		return s.metadump()
+	case "version":
+		return "VERSION 1.6.0\r\n"
+	case "stats":
+		return fmt.Sprintf("STAT pid 1\r\nSTAT uptime 60\r\nSTAT curr_items %d\r\nSTAT get_hits 3\r\nSTAT get_misses 1\r\nEND\r\n", len(s.data))
+	case "flush_all":
+		s.data = map[string]*metaStandInItem{}
+		return "OK\r\n"
+	default:
+		return "ERROR\r\n"
+	}
+
+	if quiet && (status == "EN" || status == "HD" || status == "NF") {
+		return ""
+	}
+	if o, ok := flags['O']; ok && status != "VA" && (fields[0] != "mg" || status == "EN") {
+		resp += " O" + o
+	}
+	if status != "VA" {
+		return status + resp + "\r\n"
+	}
+	return resp
+}
+
+func (s *metaStandIn) metadump() string {
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		item := s.data[k]
+		fmt.Fprintf(&sb, "key=%s exp=-1 la=0 cas=%d fetch=no cls=1 size=%d\n", url.QueryEscape(k), item.cas, len(item.value))
+	}
+	sb.WriteString("END\r\n")
+	return sb.String()
+}
+
+func (s *metaStandIn) get(key string, flags map[byte]string) (string, string) {
+	item, ok := s.data[key]
+	won := false
+	if !ok {
+		if _, ok = flags['N']; !ok {
+			return "EN", ""
+		}
+		ttl, _ := strconv.Atoi(flags['N'])
+		item = &metaStandInItem{ttl: ttl, cas: s.nextCAS()}
+		s.data[key] = item
+		won = true
+	}
+	if t, ok := flags['R']; ok {
+		recache, _ := strconv.Atoi(t)
+		if item.ttl > 0 && item.ttl < recache && !item.winSent {
+			won = true
+		}
+	}
+	if t, ok := flags['T']; ok {
+		item.ttl, _ = strconv.Atoi(t)
+	}
+
+	ret := []string{"VA", strconv.Itoa(len(item.value))}
+	if _, ok = flags['t']; ok {
+		ttl := -1
+		if item.ttl > 0 {
+			ttl = item.ttl
+		}
+		ret = append(ret, "t"+strconv.Itoa(ttl))
+	}
+	if _, ok = flags['c']; ok {
+		ret = append(ret, "c"+strconv.FormatUint(item.cas, 10))
+	}
+	if o, ok := flags['O']; ok {
+		ret = append(ret, "O"+o)
+	}
+	switch {
+	case won:
+		item.winSent = true
+		ret = append(ret, "W")
+	case item.winSent:
+		ret = append(ret, "Z")
+	}
+
+	if _, ok = flags['v']; !ok {
+		return "HD", strings.Join(append([]string{""}, ret[2:]...), " ")
+	}
+	return "VA", strings.Join(ret, " ") + "\r\n" + string(item.value) + "\r\n"
+}
+
+func (s *metaStandIn) set(key string, value []byte, flags map[byte]string) string {
+	item, ok := s.data[key]
+	if c, hasCAS := flags['C']; hasCAS {
+		switch {
+		case !ok:
+			return "NF"
+		case c != strconv.FormatUint(item.cas, 10):
+			return "EX"
+		}
+	}
+	switch flags['M'] {
+	case "E":
+		if ok {
+			return "NS"
+		}
+	case "R":
+		if !ok {
+			return "NS"
+		}
+	}
+
+	ttl, _ := strconv.Atoi(flags['T'])
+	s.data[key] = &metaStandInItem{value: value, ttl: ttl, cas: s.nextCAS()}
+	return "HD"
+}
+
+func (s *metaStandIn) arithmetic(key string, flags map[byte]string) (string, string) {
+	item, ok := s.data[key]
+	if !ok {
+		return "NF", ""
+	}
+	if c, hasCAS := flags['C']; hasCAS && c != strconv.FormatUint(item.cas, 10) {
+		return "EX", ""
+	}
+	n, err := strconv.ParseUint(string(item.value), 10, 64)
+	if err != nil {
+		return "CLIENT_ERROR", " cannot increment or decrement non-numeric value"
+	}
+
+	delta, _ := strconv.ParseUint(flags['D'], 10, 64)
+	switch {
+	case flags['M'] != "D":
+		n += delta
+	case delta > n:
+		n = 0
+	default:
+		n -= delta
+	}
+	item.value = []byte(strconv.FormatUint(n, 10))
+	item.cas = s.nextCAS()
+
+	return "VA", "VA " + strconv.Itoa(len(item.value)) + "\r\n" + string(item.value) + "\r\n"
+}
+
+func (s *metaStandIn) nextCAS() uint64 {
+	s.cas++
+	return s.cas
+}
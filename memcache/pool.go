@@ -0,0 +1,365 @@
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/hamba/cache/v2"
+)
+
+// DialFunc connects to the given address.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// conn is a pooled server connection.
+type conn struct {
+	nc   net.Conn
+	rw   *bufio.ReadWriter
+	addr string
+
+	// pending is the number of responses that must be discarded
+	// before the connection can be reused.
+	pending int
+}
+
+// pool is a connection pool for a set of servers.
+type pool struct {
+	selector  memcache.ServerSelector
+	dialer    DialFunc
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	maxIdle   int
+
+	// onConnect is called on each new connection.
+	onConnect func(*conn) error
+	// drain discards the pending responses of a connection.
+	drain func(*conn) error
+	// resumable determines if the connection can be reused after the error.
+	resumable func(error) bool
+
+	mu   sync.Mutex
+	free map[string][]*conn
+
+	// hits and misses count the connections reused and dialed,
+	// and open the connections not closed.
+	hits   uint64
+	misses uint64
+	open   int
+}
+
+func newPool(ss memcache.ServerSelector, dialer DialFunc, tlsConfig *tls.Config, opts []OptsFunc) *pool {
+	// The options are applied to a text client to share the
+	// common configuration.
+	mc := &memcache.Client{}
+	for _, opt := range opts {
+		opt(mc)
+	}
+
+	p := &pool{
+		selector:  ss,
+		dialer:    dialer,
+		tlsConfig: tlsConfig,
+		timeout:   mc.Timeout,
+		maxIdle:   mc.MaxIdleConns,
+		free:      map[string][]*conn{},
+	}
+	if p.dialer == nil {
+		p.dialer = (&net.Dialer{}).DialContext
+	}
+	if p.timeout == 0 {
+		p.timeout = memcache.DefaultTimeout
+	}
+	if p.maxIdle <= 0 {
+		p.maxIdle = memcache.DefaultMaxIdleConns
+	}
+
+	return p
+}
+
+// withKeyConn calls fn with a connection to the server of the given key.
+func (p *pool) withKeyConn(key string, fn func(*conn) error) error {
+	if !legalKey(key) {
+		return memcache.ErrMalformedKey
+	}
+
+	addr, err := p.selector.PickServer(key)
+	if err != nil {
+		return err
+	}
+	return p.withConn(addr, fn)
+}
+
+// withKeysConn groups the keys by server, and calls fn concurrently with
+// a connection to each server and its keys.
+func (p *pool) withKeysConn(keys []string, fn func(*conn, []string) error) error {
+	byAddr := map[string][]string{}
+	addrs := map[string]net.Addr{}
+	for _, k := range keys {
+		if !legalKey(k) {
+			return memcache.ErrMalformedKey
+		}
+
+		addr, err := p.selector.PickServer(k)
+		if err != nil {
+			return err
+		}
+		byAddr[addr.String()] = append(byAddr[addr.String()], k)
+		addrs[addr.String()] = addr
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(byAddr))
+	for a, ks := range byAddr {
+		wg.Add(1)
+		go func(addr net.Addr, ks []string) {
+			defer wg.Done()
+
+			errs <- p.withConn(addr, func(cn *conn) error {
+				return fn(cn, ks)
+			})
+		}(addrs[a], ks)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withKeyIndexesConn groups the key indexes by server, and calls fn
+// concurrently with a connection to each server and its key indexes.
+//
+// Keys that already have an error are skipped. The errors of keys that
+// cannot be sent to a server are set in errs.
+func (p *pool) withKeyIndexesConn(keys []string, errs []error, fn func(*conn, []int) error) {
+	byAddr := map[string][]int{}
+	addrs := map[string]net.Addr{}
+	for i, k := range keys {
+		if errs[i] != nil {
+			continue
+		}
+		if !legalKey(k) {
+			errs[i] = memcache.ErrMalformedKey
+			continue
+		}
+
+		addr, err := p.selector.PickServer(k)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		byAddr[addr.String()] = append(byAddr[addr.String()], i)
+		addrs[addr.String()] = addr
+	}
+
+	var wg sync.WaitGroup
+	for a, idxs := range byAddr {
+		wg.Add(1)
+		go func(addr net.Addr, idxs []int) {
+			defer wg.Done()
+
+			err := p.withConn(addr, func(cn *conn) error {
+				return fn(cn, idxs)
+			})
+			if err == nil {
+				return
+			}
+			for _, i := range idxs {
+				if errs[i] == nil {
+					errs[i] = err
+				}
+			}
+		}(addrs[a], idxs)
+	}
+	wg.Wait()
+}
+
+// withConn calls fn with a connection to the given server.
+func (p *pool) withConn(addr net.Addr, fn func(*conn) error) error {
+	cn, err := p.get(addr)
+	if err != nil {
+		return err
+	}
+
+	if err = cn.nc.SetDeadline(time.Now().Add(p.timeout)); err != nil {
+		_ = p.close(cn)
+		return err
+	}
+
+	if cn.pending > 0 {
+		if err = p.drain(cn); err != nil {
+			_ = p.close(cn)
+			return err
+		}
+	}
+
+	err = fn(cn)
+	if err != nil && !p.resumable(err) {
+		_ = p.close(cn)
+		return err
+	}
+
+	p.put(cn)
+	return err
+}
+
+// each calls fn with a connection to each server in turn.
+func (p *pool) each(fn func(*conn) error) error {
+	return p.selector.Each(func(addr net.Addr) error {
+		return p.withConn(addr, fn)
+	})
+}
+
+// closeIdle closes the idle connections.
+func (p *pool) closeIdle() error {
+	p.mu.Lock()
+	free := p.free
+	p.free = map[string][]*conn{}
+	p.mu.Unlock()
+
+	var err error
+	for _, conns := range free {
+		for _, cn := range conns {
+			if cerr := p.close(cn); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+func (p *pool) get(addr net.Addr) (*conn, error) {
+	p.mu.Lock()
+	free := p.free[addr.String()]
+	if len(free) > 0 {
+		cn := free[len(free)-1]
+		p.free[addr.String()] = free[:len(free)-1]
+		p.hits++
+		p.mu.Unlock()
+		return cn, nil
+	}
+	p.misses++
+	p.mu.Unlock()
+
+	cn, err := p.dial(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.open++
+	p.mu.Unlock()
+	return cn, nil
+}
+
+func (p *pool) put(cn *conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.free[cn.addr]) >= p.maxIdle {
+		_ = cn.nc.Close()
+		p.open--
+		return
+	}
+	p.free[cn.addr] = append(p.free[cn.addr], cn)
+}
+
+// close closes the connection.
+func (p *pool) close(cn *conn) error {
+	p.mu.Lock()
+	p.open--
+	p.mu.Unlock()
+
+	return cn.nc.Close()
+}
+
+// stats returns the statistics of the pool.
+func (p *pool) stats() cache.PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var idle int
+	for _, conns := range p.free {
+		idle += len(conns)
+	}
+	return cache.PoolStats{
+		Hits:       p.hits,
+		Misses:     p.misses,
+		TotalConns: p.open,
+		IdleConns:  idle,
+	}
+}
+
+func (p *pool) dial(addr net.Addr) (*conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	nc, err := p.dialer(ctx, addr.Network(), addr.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tlsConfig != nil {
+		tc := tls.Client(nc, clientTLSConfig(p.tlsConfig, addr.String()))
+		if err = tc.HandshakeContext(ctx); err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+		nc = tc
+	}
+
+	cn := &conn{
+		nc:   nc,
+		rw:   bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc)),
+		addr: addr.String(),
+	}
+
+	if p.onConnect != nil {
+		if err = nc.SetDeadline(time.Now().Add(p.timeout)); err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+		if err = p.onConnect(cn); err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+func legalKey(key string) bool {
+	if len(key) > 250 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// clientTLSConfig returns the TLS configuration for the address, defaulting
+// the server name to the host of the address like tls.Dial.
+func clientTLSConfig(cfg *tls.Config, addr string) *tls.Config {
+	if cfg.ServerName != "" {
+		return cfg
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	c := cfg.Clone()
+	c.ServerName = host
+	return c
+}
@@ -0,0 +1,102 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/scan"
+)
+
+// errScanAborted is returned when a dump is not read to the end,
+// so the connection is not reused.
+var errScanAborted = errors.New("memcache: scan aborted")
+
+// Scan calls fn with each key matching the pattern, using the
+// lru_crawler metadump command, available since memcached 1.4.31.
+//
+// Each server is dumped in turn, and the keys are matched as they are
+// read. Pausing after each batch slows the dump down, as the server
+// waits for the keys to be read.
+func (c *Meta) Scan(ctx context.Context, pattern string, opts cache.ScanOptions, fn func(key string) error) error {
+	return c.pool.selector.Each(func(addr net.Addr) error {
+		var scanErr error
+		err := c.pool.withConn(addr, func(cn *conn) error {
+			if scanErr = metadump(ctx, cn, c.pool.timeout, pattern, opts, fn); scanErr != nil {
+				return errScanAborted
+			}
+			return nil
+		})
+		if scanErr != nil {
+			return scanErr
+		}
+		return err
+	})
+}
+
+func metadump(
+	ctx context.Context,
+	cn *conn,
+	timeout time.Duration,
+	pattern string,
+	opts cache.ScanOptions,
+	fn func(string) error,
+) error {
+	if _, err := cn.rw.WriteString("lru_crawler metadump all\r\n"); err != nil {
+		return err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return err
+	}
+
+	t := scan.NewThrottle(opts)
+	for {
+		if err := cn.nc.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return err
+		}
+		line, err := cn.rw.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "END":
+			return nil
+		case strings.HasPrefix(line, "BUSY"), strings.HasPrefix(line, "ERROR"),
+			strings.HasPrefix(line, "CLIENT_ERROR"), strings.HasPrefix(line, "SERVER_ERROR"):
+			return &ServerError{Message: line}
+		}
+
+		key, ok := metadumpKey(line)
+		if !ok || !scan.Match(pattern, key) {
+			continue
+		}
+		if err = fn(key); err != nil {
+			return err
+		}
+		if err = t.Key(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// metadumpKey returns the key of a metadump line, in the
+// form key=<url encoded key> exp=<exp> ...
+func metadumpKey(line string) (string, bool) {
+	for _, f := range strings.Fields(line) {
+		if !strings.HasPrefix(f, "key=") {
+			continue
+		}
+		key, err := url.QueryUnescape(f[len("key="):])
+		if err != nil {
+			return "", false
+		}
+		return key, true
+	}
+	return "", false
+}
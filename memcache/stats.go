@@ -0,0 +1,145 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/hamba/cache/v2"
+)
+
+// statsClient is a client that can read the server statistics.
+type statsClient interface {
+	stats() (cache.Stats, error)
+}
+
+// Stats returns the statistics of the servers, and of the connection pool.
+//
+// The text protocol client does not expose its connection pool, so the
+// pool statistics are those of the connections used for meta protocol
+// operations.
+func (c Memcache) Stats(ctx context.Context) (cache.Stats, error) {
+	if sc, ok := c.client.(statsClient); ok {
+		return sc.stats()
+	}
+	if c.meta == nil {
+		return cache.Stats{}, ErrNotSupported
+	}
+	return c.meta.Stats(ctx)
+}
+
+// Stats returns the statistics of the servers, and of the connection pool.
+func (c *Meta) Stats(_ context.Context) (cache.Stats, error) {
+	var stats cache.Stats
+	err := c.pool.each(func(cn *conn) error {
+		s, err := readTextStats(cn)
+		if err != nil {
+			return err
+		}
+		stats = stats.Add(s)
+		return nil
+	})
+	if err != nil {
+		return cache.Stats{}, err
+	}
+
+	stats.Pool = c.pool.stats()
+	return stats, nil
+}
+
+func readTextStats(cn *conn) (cache.Stats, error) {
+	if _, err := cn.rw.WriteString("stats\r\n"); err != nil {
+		return cache.Stats{}, err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return cache.Stats{}, err
+	}
+
+	vals := map[string]string{}
+	for {
+		line, err := cn.rw.ReadString('\n')
+		if err != nil {
+			return cache.Stats{}, err
+		}
+		line = strings.TrimSpace(line)
+
+		fields := strings.Fields(line)
+		switch {
+		case line == "END":
+			return parseStats(vals), nil
+		case len(fields) == 3 && fields[0] == "STAT":
+			vals[fields[1]] = fields[2]
+		case strings.HasPrefix(line, "ERROR"), strings.HasPrefix(line, "CLIENT_ERROR"),
+			strings.HasPrefix(line, "SERVER_ERROR"):
+			return cache.Stats{}, &ServerError{Message: line}
+		default:
+			return cache.Stats{}, fmt.Errorf("memcache: unexpected response %q", line)
+		}
+	}
+}
+
+// stats returns the statistics of the servers, and of the connection pool.
+func (c *binaryClient) stats() (cache.Stats, error) {
+	var stats cache.Stats
+	err := c.pool.each(func(cn *conn) error {
+		s, err := readBinaryStats(cn)
+		if err != nil {
+			return err
+		}
+		stats = stats.Add(s)
+		return nil
+	})
+	if err != nil {
+		return cache.Stats{}, err
+	}
+
+	stats.Pool = c.pool.stats()
+	return stats, nil
+}
+
+// readBinaryStats reads the statistics, sent as a response per
+// statistic and terminated by a response without a key.
+func readBinaryStats(cn *conn) (cache.Stats, error) {
+	if err := writePacket(cn, binaryPacket{binaryHeader: binaryHeader{opcode: opStat}}); err != nil {
+		return cache.Stats{}, err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return cache.Stats{}, err
+	}
+
+	vals := map[string]string{}
+	for {
+		resp, err := readPacket(cn)
+		if err != nil {
+			return cache.Stats{}, err
+		}
+		if err = statusError(resp.status, memcache.ErrCacheMiss); err != nil {
+			return cache.Stats{}, err
+		}
+		if resp.key == "" {
+			return parseStats(vals), nil
+		}
+		vals[resp.key] = string(resp.value)
+	}
+}
+
+// parseStats returns the statistics from the memcached stat values.
+func parseStats(vals map[string]string) cache.Stats {
+	parseInt := func(name string) int64 {
+		n, _ := strconv.ParseInt(vals[name], 10, 64)
+		return n
+	}
+
+	return cache.Stats{
+		Items:       parseInt("curr_items"),
+		Bytes:       parseInt("bytes"),
+		Evictions:   parseInt("evictions"),
+		Hits:        parseInt("get_hits"),
+		Misses:      parseInt("get_misses"),
+		Connections: parseInt("curr_connections"),
+		Uptime:      time.Duration(parseInt("uptime")) * time.Second,
+	}
+}
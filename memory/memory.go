@@ -0,0 +1,296 @@
+// Package memory implements a bounded in-process cache.
+package memory
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/decoder"
+	"github.com/hamba/cache/v2/internal/scan"
+)
+
+// OptsFunc represents an configuration function for Memory.
+type OptsFunc func(*Memory)
+
+// WithMaxEntries configures the maximum number of items in the cache,
+// evicting the least recently used item when it is exceeded. The default
+// is 10000, and zero disables the bound.
+func WithMaxEntries(n int) OptsFunc {
+	return func(m *Memory) {
+		m.maxEntries = n
+	}
+}
+
+// WithClock configures the function returning the current time.
+func WithClock(now func() time.Time) OptsFunc {
+	return func(m *Memory) {
+		m.now = now
+	}
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// Memory is an in-process cache.
+type Memory struct {
+	maxEntries int
+	now        func() time.Time
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+
+	dec cache.Decoder
+}
+
+// New returns an in-process cache.
+func New(opts ...OptsFunc) *Memory {
+	m := &Memory{
+		maxEntries: 10000,
+		now:        time.Now,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+		dec:        decoder.StringDecoder{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Get gets the item for the given key.
+func (m *Memory) Get(_ context.Context, key string) cache.Item {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.get(key)
+	if !ok {
+		return cache.NewItem(m.dec, []byte(nil), cache.ErrCacheMiss)
+	}
+	return cache.NewItem(m.dec, e.value, nil)
+}
+
+// GetMulti gets the items for the given keys.
+func (m *Memory) GetMulti(ctx context.Context, keys ...string) ([]cache.Item, error) {
+	items := make([]cache.Item, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, m.Get(ctx, k))
+	}
+	return items, nil
+}
+
+// Set sets the item in the cache.
+func (m *Memory) Set(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	v, err := encode(value)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.set(key, v, expire)
+	return nil
+}
+
+// Add sets the item in the cache, but only if the key does not already exist.
+func (m *Memory) Add(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	v, err := encode(value)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.get(key); ok {
+		return cache.ErrNotStored
+	}
+	m.set(key, v, expire)
+	return nil
+}
+
+// Replace sets the item in the cache, but only if the key already exists.
+func (m *Memory) Replace(_ context.Context, key string, value interface{}, expire time.Duration) error {
+	v, err := encode(value)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.get(key); !ok {
+		return cache.ErrNotStored
+	}
+	m.set(key, v, expire)
+	return nil
+}
+
+// Delete deletes the item with the given key.
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.items[key]; ok {
+		m.remove(el)
+	}
+	return nil
+}
+
+// Inc increments a key by the value. A missing key is created at zero.
+func (m *Memory) Inc(_ context.Context, key string, value uint64) (int64, error) {
+	return m.incrBy(key, int64(value))
+}
+
+// Dec decrements a key by the value. A missing key is created at zero.
+func (m *Memory) Dec(_ context.Context, key string, value uint64) (int64, error) {
+	return m.incrBy(key, -int64(value))
+}
+
+func (m *Memory) incrBy(key string, delta int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	e, ok := m.get(key)
+	if ok {
+		var err error
+		n, err = strconv.ParseInt(string(e.value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("memory: value is not an integer: %w", err)
+		}
+	}
+	n += delta
+
+	v := []byte(strconv.FormatInt(n, 10))
+	if ok {
+		e.value = v
+		return n, nil
+	}
+	m.set(key, v, 0)
+	return n, nil
+}
+
+// Flush removes all items from the cache.
+func (m *Memory) Flush(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ll.Init()
+	m.items = map[string]*list.Element{}
+	return nil
+}
+
+// Scan calls fn with each key matching the pattern. The keys are
+// taken from a snapshot of the cache, in most recently used order.
+func (m *Memory) Scan(ctx context.Context, pattern string, opts cache.ScanOptions, fn func(key string) error) error {
+	m.mu.Lock()
+	now := m.now()
+	keys := make([]string, 0, m.ll.Len())
+	for el := m.ll.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*entry)
+		if !e.expires.IsZero() && !now.Before(e.expires) {
+			continue
+		}
+		if scan.Match(pattern, e.key) {
+			keys = append(keys, e.key)
+		}
+	}
+	m.mu.Unlock()
+
+	t := scan.NewThrottle(opts)
+	for _, k := range keys {
+		if err := fn(k); err != nil {
+			return err
+		}
+		if err := t.Key(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Len returns the number of items in the cache, including expired
+// items that have not been removed.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ll.Len()
+}
+
+// get returns the unexpired entry for the key, marking it as recently used.
+func (m *Memory) get(key string) (*entry, bool) {
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if !e.expires.IsZero() && !m.now().Before(e.expires) {
+		m.remove(el)
+		return nil, false
+	}
+
+	m.ll.MoveToFront(el)
+	return e, true
+}
+
+func (m *Memory) set(key string, value []byte, expire time.Duration) {
+	var expires time.Time
+	if expire > 0 {
+		expires = m.now().Add(expire)
+	}
+
+	if el, ok := m.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value, e.expires = value, expires
+		m.ll.MoveToFront(el)
+		return
+	}
+
+	m.items[key] = m.ll.PushFront(&entry{key: key, value: value, expires: expires})
+	if m.maxEntries > 0 && m.ll.Len() > m.maxEntries {
+		m.remove(m.ll.Back())
+	}
+}
+
+func (m *Memory) remove(el *list.Element) {
+	m.ll.Remove(el)
+	delete(m.items, el.Value.(*entry).key)
+}
+
+func encode(v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case []byte:
+		return append([]byte(nil), val...), nil
+	case string:
+		return []byte(val), nil
+	case bool:
+		if val {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case int, int8, int16, int32, int64:
+		return []byte(fmt.Sprintf("%d", v)), nil
+	case uint, uint8, uint16, uint32, uint64:
+		return []byte(fmt.Sprintf("%d", v)), nil
+	case float32:
+		return strconv.AppendFloat(nil, float64(val), 'f', -1, 32), nil
+	case float64:
+		return strconv.AppendFloat(nil, val, 'f', -1, 64), nil
+	}
+
+	return json.Marshal(v)
+}
@@ -0,0 +1,183 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	c := memory.New()
+
+	assert.Implements(t, (*cache.Cache)(nil), c)
+
+	err := c.Set(ctx, "test", "foobar", 0)
+	require.NoError(t, err)
+
+	str, err := c.Get(ctx, "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+
+	err = c.Add(ctx, "test", "foobar", 0)
+	assert.ErrorIs(t, err, cache.ErrNotStored)
+
+	err = c.Replace(ctx, "test", "baz", 0)
+	require.NoError(t, err)
+
+	err = c.Replace(ctx, "_", "baz", 0)
+	assert.ErrorIs(t, err, cache.ErrNotStored)
+
+	items, err := c.GetMulti(ctx, "test", "_")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	str, err = items[0].String()
+	require.NoError(t, err)
+	assert.Equal(t, "baz", str)
+	assert.ErrorIs(t, items[1].Err, cache.ErrCacheMiss)
+
+	err = c.Delete(ctx, "test")
+	require.NoError(t, err)
+
+	_, err = c.Get(ctx, "test").String()
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestMemory_Encoding(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "bytes", value: []byte("foo"), want: "foo"},
+		{name: "bool", value: true, want: "1"},
+		{name: "int", value: -1, want: "-1"},
+		{name: "uint", value: uint8(2), want: "2"},
+		{name: "float", value: 1.25, want: "1.25"},
+		{name: "struct", value: struct{ A int }{A: 1}, want: `{"A":1}`},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			c := memory.New()
+
+			err := c.Set(context.Background(), "test", test.value, 0)
+			require.NoError(t, err)
+
+			str, err := c.Get(context.Background(), "test").String()
+			require.NoError(t, err)
+			assert.Equal(t, test.want, str)
+		})
+	}
+}
+
+func TestMemory_IncDec(t *testing.T) {
+	ctx := context.Background()
+	c := memory.New()
+
+	n, err := c.Inc(ctx, "counter", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	n, err = c.Dec(ctx, "counter", 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), n)
+
+	err = c.Set(ctx, "test", "foo", 0)
+	require.NoError(t, err)
+
+	_, err = c.Inc(ctx, "test", 1)
+	assert.Error(t, err)
+}
+
+func TestMemory_Expiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := memory.New(memory.WithClock(func() time.Time { return now }))
+
+	err := c.Set(ctx, "test", "foobar", time.Minute)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+
+	_, err = c.Get(ctx, "test").String()
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestMemory_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := memory.New(memory.WithMaxEntries(2))
+
+	_ = c.Set(ctx, "a", "1", 0)
+	_ = c.Set(ctx, "b", "2", 0)
+	_ = c.Get(ctx, "a")
+	_ = c.Set(ctx, "c", "3", 0)
+
+	assert.Equal(t, 2, c.Len())
+	assert.NoError(t, c.Get(ctx, "a").Err)
+	assert.ErrorIs(t, c.Get(ctx, "b").Err, cache.ErrCacheMiss)
+	assert.NoError(t, c.Get(ctx, "c").Err)
+}
+
+func TestMemory_Flush(t *testing.T) {
+	ctx := context.Background()
+	c := memory.New()
+
+	assert.Implements(t, (*cache.Flusher)(nil), c)
+
+	_ = c.Set(ctx, "a", "1", 0)
+	_ = c.Set(ctx, "b", "2", 0)
+
+	err := c.Flush(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, c.Len())
+	assert.ErrorIs(t, c.Get(ctx, "a").Err, cache.ErrCacheMiss)
+}
+
+func TestMemory_Scan(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := memory.New(memory.WithClock(func() time.Time { return now }))
+
+	assert.Implements(t, (*cache.Scanner)(nil), c)
+
+	_ = c.Set(ctx, "user:1", "a", 0)
+	_ = c.Set(ctx, "user:2", "b", 0)
+	_ = c.Set(ctx, "user:3", "c", time.Second)
+	_ = c.Set(ctx, "item:1", "d", 0)
+	now = now.Add(time.Second)
+
+	var keys []string
+	err := c.Scan(ctx, "user:*", cache.ScanOptions{BatchSize: 1}, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user:1", "user:2"}, keys)
+}
+
+func TestMemory_ScanStopsOnError(t *testing.T) {
+	ctx := context.Background()
+	c := memory.New()
+
+	_ = c.Set(ctx, "a", "1", 0)
+	_ = c.Set(ctx, "b", "2", 0)
+
+	var n int
+	err := c.Scan(ctx, "*", cache.ScanOptions{}, func(string) error {
+		n++
+		return cache.ErrNotStored
+	})
+
+	assert.ErrorIs(t, err, cache.ErrNotStored)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Entry is an item to be set in the cache.
+type Entry struct {
+	Key    string
+	Value  interface{}
+	Expire time.Duration
+}
+
+// MultiCache represents a cache instance supporting batched writes.
+type MultiCache interface {
+	Cache
+
+	// SetMulti sets the entries in the cache. The errors are returned
+	// in the order of the entries, with nil for each successful write.
+	SetMulti(ctx context.Context, es ...Entry) []error
+
+	// DeleteMulti deletes the items with the given keys. The errors are
+	// returned in the order of the keys, with nil for each successful delete.
+	DeleteMulti(ctx context.Context, ks ...string) []error
+}
+
+// SetMulti sets the entries in the cache, using a batched write if
+// the cache is a MultiCache, otherwise setting each entry in turn.
+func SetMulti(ctx context.Context, c Cache, es ...Entry) []error {
+	if mc, ok := c.(MultiCache); ok {
+		return mc.SetMulti(ctx, es...)
+	}
+
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = c.Set(ctx, e.Key, e.Value, e.Expire)
+	}
+	return errs
+}
+
+// DeleteMulti deletes the items with the given keys, using a batched delete
+// if the cache is a MultiCache, otherwise deleting each item in turn.
+func DeleteMulti(ctx context.Context, c Cache, ks ...string) []error {
+	if mc, ok := c.(MultiCache); ok {
+		return mc.DeleteMulti(ctx, ks...)
+	}
+
+	errs := make([]error, len(ks))
+	for i, k := range ks {
+		errs[i] = c.Delete(ctx, k)
+	}
+	return errs
+}
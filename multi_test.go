@@ -0,0 +1,107 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMulti(t *testing.T) {
+	c := newRecordingCache()
+
+	errs := cache.SetMulti(context.Background(), c,
+		cache.Entry{Key: "test1", Value: "foo", Expire: time.Minute},
+		cache.Entry{Key: "fail", Value: "bar"},
+	)
+
+	assert.Equal(t, []error{nil, errTest}, errs)
+	assert.Equal(t, []string{"set test1", "set fail"}, c.calls)
+	assert.NoError(t, c.Get(context.Background(), "test1").Err)
+}
+
+func TestSetMulti_UsesMultiCache(t *testing.T) {
+	c := &recordingMultiCache{recordingCache: newRecordingCache()}
+
+	errs := cache.SetMulti(context.Background(), c, cache.Entry{Key: "test1", Value: "foo"})
+
+	assert.Equal(t, []error{nil}, errs)
+	assert.Equal(t, []string{"setmulti test1"}, c.calls)
+}
+
+func TestDeleteMulti(t *testing.T) {
+	c := newRecordingCache()
+
+	errs := cache.DeleteMulti(context.Background(), c, "test1", "fail")
+
+	assert.Equal(t, []error{nil, errTest}, errs)
+	assert.Equal(t, []string{"delete test1", "delete fail"}, c.calls)
+}
+
+func TestDeleteMulti_UsesMultiCache(t *testing.T) {
+	c := &recordingMultiCache{recordingCache: newRecordingCache()}
+
+	errs := cache.DeleteMulti(context.Background(), c, "test1")
+
+	assert.Equal(t, []error{nil}, errs)
+	assert.Equal(t, []string{"deletemulti test1"}, c.calls)
+}
+
+var errTest = errors.New("test")
+
+// recordingCache records its write calls, failing for the key "fail".
+type recordingCache struct {
+	*memory.Memory
+
+	calls []string
+}
+
+func newRecordingCache() *recordingCache {
+	return &recordingCache{Memory: memory.New()}
+}
+
+func (c *recordingCache) record(call, key string) error {
+	c.calls = append(c.calls, call+" "+key)
+	if key == "fail" {
+		return errTest
+	}
+	return nil
+}
+
+func (c *recordingCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	if err := c.record("set", key); err != nil {
+		return err
+	}
+	return c.Memory.Set(ctx, key, value, expire)
+}
+
+func (c *recordingCache) Delete(ctx context.Context, key string) error {
+	if err := c.record("delete", key); err != nil {
+		return err
+	}
+	return c.Memory.Delete(ctx, key)
+}
+
+type recordingMultiCache struct {
+	*recordingCache
+}
+
+func (c *recordingMultiCache) SetMulti(_ context.Context, es ...cache.Entry) []error {
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = c.record("setmulti", e.Key)
+	}
+	return errs
+}
+
+func (c *recordingMultiCache) DeleteMulti(_ context.Context, ks ...string) []error {
+	errs := make([]error, len(ks))
+	for i, k := range ks {
+		errs[i] = c.record("deletemulti", k)
+	}
+	return errs
+}
@@ -0,0 +1,194 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hamba/cache/v2"
+)
+
+// bucketMaxAttempts is the maximum number of attempts of a bucket update.
+const bucketMaxAttempts = 10
+
+// BucketCache represents a cache that can atomically take tokens from
+// a token bucket.
+type BucketCache interface {
+	cache.Cache
+
+	// TakeTokens refills the bucket with the given key at rate tokens per
+	// second up to burst tokens, and takes n tokens if available. It returns
+	// the tokens left in the bucket, and if the tokens were taken.
+	TakeTokens(ctx context.Context, key string, rate float64, burst, n int64, now time.Time) (float64, bool, error)
+}
+
+// TokenBucket limits requests to a rate, allowing bursts of requests.
+//
+// The cache must be a BucketCache or a cache.CASCache.
+type TokenBucket struct {
+	cache cache.Cache
+	rate  float64
+	burst int64
+	opts  options
+}
+
+// NewTokenBucket returns a limiter allowing rate requests per second,
+// with bursts of up to burst requests. ErrInvalidLimit is returned if
+// the rate or burst is not positive.
+func NewTokenBucket(c cache.Cache, rate float64, burst int64, opts ...OptsFunc) (*TokenBucket, error) {
+	if !(rate > 0) || burst <= 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	return &TokenBucket{
+		cache: c,
+		rate:  rate,
+		burst: burst,
+		opts:  newOptions(opts),
+	}, nil
+}
+
+// Allow checks if a single request for the given key is allowed.
+func (l *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN checks if n requests for the given key are allowed.
+// ErrInvalidCount is returned if n is not positive or exceeds the burst.
+func (l *TokenBucket) AllowN(ctx context.Context, key string, n int64) (Result, error) {
+	if n <= 0 || n > l.burst {
+		return Result{}, ErrInvalidCount
+	}
+
+	k := l.opts.prefix + key
+	now := l.opts.now()
+
+	var (
+		tokens float64
+		ok     bool
+		err    error
+	)
+	switch c := l.cache.(type) {
+	case BucketCache:
+		tokens, ok, err = c.TakeTokens(ctx, k, l.rate, l.burst, n, now)
+	case cache.CASCache:
+		tokens, ok, err = l.takeCAS(ctx, c, k, n, now)
+	default:
+		return Result{}, ErrNotSupported
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{Allowed: ok, Remaining: int64(math.Floor(tokens))}
+	if !ok {
+		res.RetryAfter = time.Duration(math.Ceil((float64(n) - tokens) / l.rate * float64(time.Second)))
+	}
+	return res, nil
+}
+
+// takeCAS takes the tokens from the bucket using compare and swap.
+func (l *TokenBucket) takeCAS(
+	ctx context.Context,
+	c cache.CASCache,
+	key string,
+	n int64,
+	now time.Time,
+) (float64, bool, error) {
+	for i := 0; i < bucketMaxAttempts; i++ {
+		item := c.Gets(ctx, key)
+		if item.Err != nil && !errors.Is(item.Err, cache.ErrCacheMiss) {
+			return 0, false, item.Err
+		}
+
+		b := bucket{tokens: float64(l.burst), last: now}
+		if item.Err == nil {
+			s, err := item.String()
+			if err != nil {
+				return 0, false, err
+			}
+			if b, err = parseBucket(s); err != nil {
+				return 0, false, err
+			}
+		}
+
+		b.refill(now, l.rate, l.burst)
+		if b.tokens < float64(n) {
+			return b.tokens, false, nil
+		}
+		b.tokens -= float64(n)
+
+		var err error
+		expire := b.expire(l.rate, l.burst)
+		if item.Err != nil {
+			err = c.Add(ctx, key, b.String(), expire)
+		} else {
+			err = c.CompareAndSwap(ctx, key, b.String(), item.Token, expire)
+		}
+		switch {
+		case err == nil:
+			return b.tokens, true, nil
+		case !errors.Is(err, cache.ErrNotStored) &&
+			!errors.Is(err, cache.ErrCASConflict) &&
+			!errors.Is(err, cache.ErrCacheMiss):
+			return 0, false, err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return 0, false, err
+		}
+	}
+
+	return 0, false, fmt.Errorf("ratelimit: bucket update failed after %d attempts: %w",
+		bucketMaxAttempts, cache.ErrCASConflict)
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func parseBucket(s string) (bucket, error) {
+	parts := strings.SplitN(s, " ", 2)
+	if len(parts) != 2 {
+		return bucket{}, errors.New("ratelimit: invalid bucket")
+	}
+
+	tokens, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return bucket{}, fmt.Errorf("ratelimit: invalid bucket tokens: %w", err)
+	}
+	ms, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return bucket{}, fmt.Errorf("ratelimit: invalid bucket time: %w", err)
+	}
+
+	return bucket{tokens: tokens, last: time.UnixMilli(ms)}, nil
+}
+
+// refill adds the tokens accumulated since the bucket was last refilled.
+func (b *bucket) refill(now time.Time, rate float64, burst int64) {
+	if !now.After(b.last) {
+		return
+	}
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+}
+
+// expire returns the duration until the bucket is full, after
+// which its state is no longer needed.
+func (b bucket) expire(rate float64, burst int64) time.Duration {
+	full := time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second))
+	return full.Truncate(time.Second) + time.Second
+}
+
+// String returns the bucket as stored in the cache.
+func (b bucket) String() string {
+	return strconv.FormatFloat(b.tokens, 'f', -1, 64) + " " + strconv.FormatInt(b.last.UnixMilli(), 10)
+}
@@ -0,0 +1,48 @@
+package ratelimit_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/hamba/cache/v2/ratelimit"
+	"github.com/hamba/cache/v2/redis"
+)
+
+func ExampleNewTokenBucket() {
+	c, err := redis.New("redis://localhost:6379")
+	if err != nil {
+		// Handle error
+	}
+
+	l, err := ratelimit.NewTokenBucket(c, 10, 20)
+	if err != nil {
+		// Handle error
+	}
+
+	res, err := l.Allow(context.Background(), "user:1")
+	if err != nil {
+		// Handle error
+	}
+	if !res.Allowed {
+		time.Sleep(res.RetryAfter)
+	}
+}
+
+func ExampleNewSlidingWindow() {
+	c, err := redis.New("redis://localhost:6379")
+	if err != nil {
+		// Handle error
+	}
+
+	l, err := ratelimit.NewSlidingWindow(c, 100, time.Minute, ratelimit.WithPrefix("api:"))
+	if err != nil {
+		// Handle error
+	}
+
+	res, err := l.Allow(context.Background(), "user:1")
+	if err != nil {
+		// Handle error
+	}
+
+	_ = res.Remaining
+}
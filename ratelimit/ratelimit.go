@@ -0,0 +1,253 @@
+// Package ratelimit implements distributed rate limiters on top of a cache.
+//
+// Fixed and sliding window limiters count requests in per-window counters,
+// using the cache counters when the cache is a cache.CounterCache, and add
+// with increment otherwise. The token bucket limiter updates the bucket
+// atomically, using the cache when it is a BucketCache, and compare and swap
+// otherwise.
+//
+// Denied requests are not counted towards the limit.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/hamba/cache/v2"
+)
+
+// ErrNotSupported is returned if the cache does not support the
+// operations required by the limiter.
+var ErrNotSupported = errors.New("ratelimit: cache not supported")
+
+// ErrInvalidLimit is returned if the limit, rate or window of a limiter
+// is not positive.
+var ErrInvalidLimit = errors.New("ratelimit: limit, rate and window must be positive")
+
+// ErrInvalidCount is returned if the number of requests is not positive,
+// or exceeds the limit or burst so the requests can never be allowed.
+var ErrInvalidCount = errors.New("ratelimit: invalid number of requests")
+
+// Result is the result of a rate limit check.
+type Result struct {
+	// Allowed determines if the request is allowed.
+	Allowed bool
+
+	// Remaining is the number of requests remaining in the limit.
+	Remaining int64
+
+	// RetryAfter is the estimated duration until the request would be
+	// allowed, or zero if it is allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter represents a rate limiter.
+type Limiter interface {
+	// Allow checks if a single request for the given key is allowed.
+	Allow(ctx context.Context, key string) (Result, error)
+
+	// AllowN checks if n requests for the given key are allowed. ErrInvalidCount
+	// is returned if n is not positive or can never be allowed.
+	AllowN(ctx context.Context, key string, n int64) (Result, error)
+}
+
+type options struct {
+	prefix string
+	now    func() time.Time
+}
+
+func newOptions(opts []OptsFunc) options {
+	o := options{prefix: "ratelimit:", now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// OptsFunc represents a configuration function for a limiter.
+type OptsFunc func(*options)
+
+// WithPrefix configures the prefix of the cache keys. The default is "ratelimit:".
+func WithPrefix(prefix string) OptsFunc {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithClock configures the function returning the current time.
+func WithClock(now func() time.Time) OptsFunc {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// FixedWindow limits the number of requests in fixed windows of time.
+type FixedWindow struct {
+	cache  cache.Cache
+	limit  int64
+	window time.Duration
+	opts   options
+}
+
+// NewFixedWindow returns a limiter allowing limit requests per window.
+// ErrInvalidLimit is returned if the limit or window is not positive.
+func NewFixedWindow(c cache.Cache, limit int64, window time.Duration, opts ...OptsFunc) (*FixedWindow, error) {
+	if limit <= 0 || window <= 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	return &FixedWindow{
+		cache:  c,
+		limit:  limit,
+		window: window,
+		opts:   newOptions(opts),
+	}, nil
+}
+
+// Allow checks if a single request for the given key is allowed.
+func (l *FixedWindow) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN checks if n requests for the given key are allowed.
+// ErrInvalidCount is returned if n is not positive or exceeds the limit.
+func (l *FixedWindow) AllowN(ctx context.Context, key string, n int64) (Result, error) {
+	if n <= 0 || n > l.limit {
+		return Result{}, ErrInvalidCount
+	}
+
+	now := l.opts.now()
+	idx := now.UnixNano() / int64(l.window)
+	k := windowKey(l.opts.prefix, key, idx)
+
+	count, err := incr(ctx, l.cache, k, n, l.window)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if count > l.limit {
+		if _, err = incr(ctx, l.cache, k, -n, l.window); err != nil {
+			return Result{}, err
+		}
+		return Result{
+			Remaining:  remaining(l.limit, float64(count-n)),
+			RetryAfter: time.Duration((idx+1)*int64(l.window) - now.UnixNano()),
+		}, nil
+	}
+
+	return Result{Allowed: true, Remaining: l.limit - count}, nil
+}
+
+// SlidingWindow limits the number of requests in a sliding window of time.
+//
+// The number of requests in the window is estimated from the counts of the
+// current and previous fixed windows, weighting the previous window by its
+// overlap with the sliding window.
+type SlidingWindow struct {
+	cache  cache.Cache
+	limit  int64
+	window time.Duration
+	opts   options
+}
+
+// NewSlidingWindow returns a limiter allowing limit requests in any window.
+// ErrInvalidLimit is returned if the limit or window is not positive.
+func NewSlidingWindow(c cache.Cache, limit int64, window time.Duration, opts ...OptsFunc) (*SlidingWindow, error) {
+	if limit <= 0 || window <= 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	return &SlidingWindow{
+		cache:  c,
+		limit:  limit,
+		window: window,
+		opts:   newOptions(opts),
+	}, nil
+}
+
+// Allow checks if a single request for the given key is allowed.
+func (l *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN checks if n requests for the given key are allowed.
+// ErrInvalidCount is returned if n is not positive or exceeds the limit.
+func (l *SlidingWindow) AllowN(ctx context.Context, key string, n int64) (Result, error) {
+	if n <= 0 || n > l.limit {
+		return Result{}, ErrInvalidCount
+	}
+
+	now := l.opts.now()
+	idx := now.UnixNano() / int64(l.window)
+	elapsed := float64(now.UnixNano()-idx*int64(l.window)) / float64(l.window)
+	k := windowKey(l.opts.prefix, key, idx)
+
+	// The counters are kept for two windows, as they are used as the
+	// previous window once the current window ends.
+	count, err := incr(ctx, l.cache, k, n, 2*l.window)
+	if err != nil {
+		return Result{}, err
+	}
+
+	prev, err := l.cache.Get(ctx, windowKey(l.opts.prefix, key, idx-1)).Int64()
+	if err != nil && !errors.Is(err, cache.ErrCacheMiss) {
+		return Result{}, err
+	}
+
+	est := float64(prev)*(1-elapsed) + float64(count)
+	if est > float64(l.limit) {
+		if _, err = incr(ctx, l.cache, k, -n, 2*l.window); err != nil {
+			return Result{}, err
+		}
+		return Result{
+			Remaining:  remaining(l.limit, est-float64(n)),
+			RetryAfter: l.retryAfter(float64(prev), float64(count), elapsed),
+		}, nil
+	}
+
+	return Result{Allowed: true, Remaining: remaining(l.limit, est)}, nil
+}
+
+// retryAfter estimates the duration until the previous window overlaps
+// the sliding window little enough to allow the requests.
+func (l *SlidingWindow) retryAfter(prev, count, elapsed float64) time.Duration {
+	if count > float64(l.limit) || prev == 0 {
+		return time.Duration((1 - elapsed) * float64(l.window))
+	}
+
+	// Solve prev * (1 - e) + count <= limit for the elapsed fraction e.
+	e := 1 - (float64(l.limit)-count)/prev
+	return time.Duration(math.Ceil((e - elapsed) * float64(l.window)))
+}
+
+// incr adds the delta to the counter with the given key, setting the
+// expiration when the counter is created.
+func incr(ctx context.Context, c cache.Cache, key string, delta int64, expire time.Duration) (int64, error) {
+	if cc, ok := c.(cache.CounterCache); ok {
+		return cc.IncWithOptions(ctx, key, delta, cache.CounterOptions{Expire: expire})
+	}
+
+	if delta < 0 {
+		return c.Dec(ctx, key, uint64(-delta))
+	}
+
+	if err := c.Add(ctx, key, "0", expire); err != nil && !errors.Is(err, cache.ErrNotStored) {
+		return 0, err
+	}
+	return c.Inc(ctx, key, uint64(delta))
+}
+
+func windowKey(prefix, key string, idx int64) string {
+	return prefix + key + ":" + strconv.FormatInt(idx, 10)
+}
+
+func remaining(limit int64, used float64) int64 {
+	r := limit - int64(math.Ceil(used))
+	if r < 0 {
+		return 0
+	}
+	return r
+}
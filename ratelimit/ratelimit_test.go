@@ -0,0 +1,396 @@
+package ratelimit_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/cachetest"
+	"github.com/hamba/cache/v2/memory"
+	"github.com/hamba/cache/v2/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiters_Implements(t *testing.T) {
+	c := memory.New()
+
+	fixed, err := ratelimit.NewFixedWindow(c, 1, time.Second)
+	require.NoError(t, err)
+	sliding, err := ratelimit.NewSlidingWindow(c, 1, time.Second)
+	require.NoError(t, err)
+	bucket, err := ratelimit.NewTokenBucket(c, 1, 1)
+	require.NoError(t, err)
+
+	assert.Implements(t, (*ratelimit.Limiter)(nil), fixed)
+	assert.Implements(t, (*ratelimit.Limiter)(nil), sliding)
+	assert.Implements(t, (*ratelimit.Limiter)(nil), bucket)
+}
+
+func TestLimiters_InvalidArguments(t *testing.T) {
+	c := memory.New()
+
+	tests := []struct {
+		name string
+		new  func() error
+	}{
+		{
+			name: "fixed window zero limit",
+			new: func() error {
+				_, err := ratelimit.NewFixedWindow(c, 0, time.Second)
+				return err
+			},
+		},
+		{
+			name: "fixed window zero window",
+			new: func() error {
+				_, err := ratelimit.NewFixedWindow(c, 1, 0)
+				return err
+			},
+		},
+		{
+			name: "sliding window negative limit",
+			new: func() error {
+				_, err := ratelimit.NewSlidingWindow(c, -1, time.Second)
+				return err
+			},
+		},
+		{
+			name: "sliding window zero window",
+			new: func() error {
+				_, err := ratelimit.NewSlidingWindow(c, 1, 0)
+				return err
+			},
+		},
+		{
+			name: "token bucket zero rate",
+			new: func() error {
+				_, err := ratelimit.NewTokenBucket(c, 0, 1)
+				return err
+			},
+		},
+		{
+			name: "token bucket zero burst",
+			new: func() error {
+				_, err := ratelimit.NewTokenBucket(c, 1, 0)
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := test.new()
+
+			assert.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+		})
+	}
+}
+
+func TestLimiters_InvalidCount(t *testing.T) {
+	c := cachetest.NewCASCache()
+	fixed, err := ratelimit.NewFixedWindow(c, 2, time.Minute)
+	require.NoError(t, err)
+	sliding, err := ratelimit.NewSlidingWindow(c, 2, time.Minute)
+	require.NoError(t, err)
+	bucket, err := ratelimit.NewTokenBucket(c, 1, 2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		limiter ratelimit.Limiter
+		n       int64
+	}{
+		{
+			name:    "fixed window zero",
+			limiter: fixed,
+			n:       0,
+		},
+		{
+			name:    "fixed window negative",
+			limiter: fixed,
+			n:       -1,
+		},
+		{
+			name:    "fixed window over limit",
+			limiter: fixed,
+			n:       3,
+		},
+		{
+			name:    "sliding window negative",
+			limiter: sliding,
+			n:       -1,
+		},
+		{
+			name:    "sliding window over limit",
+			limiter: sliding,
+			n:       3,
+		},
+		{
+			name:    "token bucket negative",
+			limiter: bucket,
+			n:       -1,
+		},
+		{
+			name:    "token bucket over burst",
+			limiter: bucket,
+			n:       3,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.limiter.AllowN(context.Background(), "test", test.n)
+
+			assert.ErrorIs(t, err, ratelimit.ErrInvalidCount)
+		})
+	}
+}
+
+func TestFixedWindow(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache cache.Cache
+	}{
+		{
+			name:  "add and increment",
+			cache: memory.New(),
+		},
+		{
+			name:  "counter cache",
+			cache: &counterCache{Memory: memory.New()},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			clock := newClock(10 * time.Second)
+			l, err := ratelimit.NewFixedWindow(test.cache, 2, time.Minute, ratelimit.WithClock(clock.Now))
+			require.NoError(t, err)
+
+			res, err := l.Allow(ctx, "test")
+			require.NoError(t, err)
+			assert.Equal(t, ratelimit.Result{Allowed: true, Remaining: 1}, res)
+
+			res, err = l.Allow(ctx, "test")
+			require.NoError(t, err)
+			assert.Equal(t, ratelimit.Result{Allowed: true, Remaining: 0}, res)
+
+			res, err = l.Allow(ctx, "test")
+			require.NoError(t, err)
+			assert.Equal(t, ratelimit.Result{RetryAfter: 50 * time.Second}, res)
+
+			clock.Add(50 * time.Second)
+
+			res, err = l.AllowN(ctx, "test", 2)
+			require.NoError(t, err)
+			assert.Equal(t, ratelimit.Result{Allowed: true, Remaining: 0}, res)
+		})
+	}
+}
+
+func TestFixedWindow_DeniedRequestsAreNotCounted(t *testing.T) {
+	ctx := context.Background()
+	clock := newClock(0)
+	l, err := ratelimit.NewFixedWindow(memory.New(), 2, time.Minute, ratelimit.WithClock(clock.Now))
+	require.NoError(t, err)
+
+	res, err := l.Allow(ctx, "test")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	res, err = l.AllowN(ctx, "test", 2)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	res, err = l.Allow(ctx, "test")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestFixedWindow_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	l, err := ratelimit.NewFixedWindow(memory.New(), 10, time.Hour)
+	require.NoError(t, err)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res, err := l.Allow(ctx, "test")
+			require.NoError(t, err)
+			if res.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, allowed, 10)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	clock := newClock(0)
+	l, err := ratelimit.NewSlidingWindow(memory.New(), 4, time.Minute, ratelimit.WithClock(clock.Now))
+	require.NoError(t, err)
+
+	res, err := l.AllowN(ctx, "test", 4)
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Result{Allowed: true, Remaining: 0}, res)
+
+	clock.Add(90 * time.Second)
+
+	// The previous window is weighted by half.
+	res, err = l.AllowN(ctx, "test", 2)
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Result{Allowed: true, Remaining: 0}, res)
+
+	res, err = l.Allow(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Result{RetryAfter: 15 * time.Second}, res)
+
+	clock.Add(15 * time.Second)
+
+	res, err = l.Allow(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Result{Allowed: true, Remaining: 0}, res)
+}
+
+func TestSlidingWindow_RetryAfterWindowWhenCurrentExceeded(t *testing.T) {
+	ctx := context.Background()
+	clock := newClock(20 * time.Second)
+	l, err := ratelimit.NewSlidingWindow(memory.New(), 2, time.Minute, ratelimit.WithClock(clock.Now))
+	require.NoError(t, err)
+
+	res, err := l.AllowN(ctx, "test", 2)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	res, err = l.Allow(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Result{RetryAfter: 40 * time.Second}, res)
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	clock := newClock(0)
+	l, err := ratelimit.NewTokenBucket(cachetest.NewCASCache(), 2, 4, ratelimit.WithClock(clock.Now))
+	require.NoError(t, err)
+
+	res, err := l.AllowN(ctx, "test", 3)
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Result{Allowed: true, Remaining: 1}, res)
+
+	res, err = l.AllowN(ctx, "test", 2)
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Result{Remaining: 1, RetryAfter: 500 * time.Millisecond}, res)
+
+	clock.Add(250 * time.Millisecond)
+
+	res, err = l.AllowN(ctx, "test", 2)
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Result{Remaining: 1, RetryAfter: 250 * time.Millisecond}, res)
+
+	clock.Add(time.Hour)
+
+	res, err = l.Allow(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Result{Allowed: true, Remaining: 3}, res)
+}
+
+func TestTokenBucket_UsesBucketCache(t *testing.T) {
+	now := time.Now()
+	c := &bucketCache{Memory: memory.New(), tokens: 1.5}
+	l, err := ratelimit.NewTokenBucket(c, 2, 4, ratelimit.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	res, err := l.AllowN(context.Background(), "test", 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, ratelimit.Result{Remaining: 1, RetryAfter: 250 * time.Millisecond}, res)
+	assert.Equal(t, []interface{}{"ratelimit:test", 2.0, int64(4), int64(2), now}, c.args)
+}
+
+func TestTokenBucket_NotSupported(t *testing.T) {
+	l, err := ratelimit.NewTokenBucket(memory.New(), 1, 1)
+	require.NoError(t, err)
+
+	_, err = l.Allow(context.Background(), "test")
+
+	assert.ErrorIs(t, err, ratelimit.ErrNotSupported)
+}
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newClock(offset time.Duration) *clock {
+	return &clock{now: time.Unix(1_600_000_020, 0).Truncate(time.Minute).Add(offset)}
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// bucketCache records the arguments of TakeTokens, returning the configured tokens.
+type bucketCache struct {
+	*memory.Memory
+
+	tokens float64
+	ok     bool
+	args   []interface{}
+}
+
+func (c *bucketCache) TakeTokens(_ context.Context, key string, rate float64, burst, n int64, now time.Time) (float64, bool, error) {
+	c.args = []interface{}{key, rate, burst, n, now}
+	return c.tokens, c.ok, nil
+}
+
+// counterCache adds counter options to the in-memory cache.
+type counterCache struct {
+	*memory.Memory
+
+	mu sync.Mutex
+}
+
+func (c *counterCache) IncWithOptions(ctx context.Context, key string, delta int64, opts cache.CounterOptions) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, err := c.Get(ctx, key).Int64()
+	if err != nil {
+		n = opts.Initial
+	}
+	n += delta
+	if n < 0 {
+		n = 0
+	}
+	return n, c.Set(ctx, key, strconv.FormatInt(n, 10), opts.Expire)
+}
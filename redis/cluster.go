@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2"
+)
+
+const slotCount = 16384
+
+// NewCluster creates a new Redis instance backed by a Redis Cluster.
+//
+// The options are applied to each node in the cluster.
+func NewCluster(addrs []string, opts ...OptsFunc) (*Redis, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("redis: at least one cluster address is required")
+	}
+
+	o := &redis.Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	c := redis.NewClusterClient(clusterOptions(addrs, o))
+
+	return NewWithClient(c), nil
+}
+
+func clusterOptions(addrs []string, o *redis.Options) *redis.ClusterOptions {
+	return &redis.ClusterOptions{
+		Addrs:              addrs,
+		Dialer:             o.Dialer,
+		OnConnect:          o.OnConnect,
+		Username:           o.Username,
+		Password:           o.Password,
+		MaxRetries:         o.MaxRetries,
+		MinRetryBackoff:    o.MinRetryBackoff,
+		MaxRetryBackoff:    o.MaxRetryBackoff,
+		DialTimeout:        o.DialTimeout,
+		ReadTimeout:        o.ReadTimeout,
+		WriteTimeout:       o.WriteTimeout,
+		PoolFIFO:           o.PoolFIFO,
+		PoolSize:           o.PoolSize,
+		MinIdleConns:       o.MinIdleConns,
+		MaxConnAge:         o.MaxConnAge,
+		PoolTimeout:        o.PoolTimeout,
+		IdleTimeout:        o.IdleTimeout,
+		IdleCheckFrequency: o.IdleCheckFrequency,
+		TLSConfig:          o.TLSConfig,
+	}
+}
+
+// getMultiCluster gets the items for the given keys from a cluster.
+//
+// Redis Cluster rejects MGET across hash slots, so the keys are grouped
+// by slot and a MGET is pipelined per slot. The cluster pipeline sends
+// the commands to each node concurrently.
+func (c Redis) getMultiCluster(ctx context.Context, conn *redis.ClusterClient, keys []string) ([]cache.Item, error) {
+	groups := groupBySlot(keys)
+
+	pipe := conn.Pipeline()
+	cmds := make([]*redis.SliceCmd, 0, len(groups))
+	for _, g := range groups {
+		ks := make([]string, 0, len(g))
+		for _, i := range g {
+			ks = append(ks, keys[i])
+		}
+		cmds = append(cmds, pipe.MGet(ctx, ks...))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	vals := make([][]interface{}, 0, len(cmds))
+	for _, cmd := range cmds {
+		vals = append(vals, cmd.Val())
+	}
+
+	items := make([]cache.Item, 0, len(keys))
+	for _, v := range ungroup(groups, vals, len(keys)) {
+		items = append(items, c.toItem(v))
+	}
+	return items, nil
+}
+
+// groupBySlot groups the indexes of the keys by hash slot, in the order
+// the slots first appear in the keys.
+func groupBySlot(keys []string) [][]int {
+	var groups [][]int
+	idx := map[int]int{}
+	for i, k := range keys {
+		s := hashSlot(k)
+		g, ok := idx[s]
+		if !ok {
+			g = len(groups)
+			idx[s] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// ungroup places the values of each group at the indexes of its keys,
+// returning the values in the order of the keys.
+func ungroup(groups [][]int, vals [][]interface{}, n int) []interface{} {
+	res := make([]interface{}, n)
+	for g, idxs := range groups {
+		for j, v := range vals[g] {
+			res[idxs[j]] = v
+		}
+	}
+	return res
+}
+
+// hashSlot returns the Redis Cluster hash slot of the given key.
+func hashSlot(key string) int {
+	if s := strings.IndexByte(key, '{'); s > -1 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+e+1]
+		}
+	}
+	return int(crc16(key) % slotCount)
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by Redis Cluster.
+func crc16(key string) uint16 {
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+				continue
+			}
+			crc <<= 1
+		}
+	}
+	return crc
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCluster(t *testing.T) {
+	c, err := NewCluster([]string{"test:7000", "test:7001"}, WithPoolSize(12), WithReadTimeout(time.Second))
+
+	require.NoError(t, err)
+	o := c.conn.(*redis.ClusterClient).Options()
+	assert.Equal(t, []string{"test:7000", "test:7001"}, o.Addrs)
+	assert.Equal(t, 12, o.PoolSize)
+	assert.Equal(t, time.Second, o.ReadTimeout)
+}
+
+func TestNewCluster_NoAddrs(t *testing.T) {
+	_, err := NewCluster(nil)
+
+	assert.Error(t, err)
+}
+
+func TestHashSlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{key: "", want: 0},
+		{key: "123456789", want: 0x31c3},
+		{key: "foo", want: 12182},
+		{key: "bar", want: 5061},
+		{key: "{user1000}.following", want: hashSlot("user1000")},
+		{key: "{user1000}.followers", want: hashSlot("user1000")},
+		{key: "foo{{bar}}zap", want: hashSlot("{bar")},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.key, func(t *testing.T) {
+			got := hashSlot(test.key)
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestGroupBySlot(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want [][]int
+	}{
+		{
+			name: "no keys",
+			keys: nil,
+			want: nil,
+		},
+		{
+			name: "single slot",
+			keys: []string{"{user1}.a", "{user1}.b", "{user1}.c"},
+			want: [][]int{{0, 1, 2}},
+		},
+		{
+			name: "interleaved slots",
+			keys: []string{"foo", "bar", "{foo}.a", "{bar}.a", "foo"},
+			want: [][]int{{0, 2, 4}, {1, 3}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			got := groupBySlot(test.keys)
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestUngroup(t *testing.T) {
+	keys := []string{"foo", "bar", "{foo}.a", "{bar}.a", "baz"}
+	groups := groupBySlot(keys)
+	vals := make([][]interface{}, 0, len(groups))
+	for _, g := range groups {
+		v := make([]interface{}, 0, len(g))
+		for _, i := range g {
+			v = append(v, keys[i])
+		}
+		vals = append(vals, v)
+	}
+
+	got := ungroup(groups, vals, len(keys))
+
+	assert.Equal(t, []interface{}{"foo", "bar", "{foo}.a", "{bar}.a", "baz"}, got)
+}
@@ -2,8 +2,11 @@ package redis_test
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2"
 	"github.com/hamba/cache/v2/redis"
 )
 
@@ -20,3 +23,174 @@ func ExampleNew() {
 
 	_, _ = i.Float64()
 }
+
+func ExampleNewCluster() {
+	c, err := redis.NewCluster([]string{"localhost:7000", "localhost:7001", "localhost:7002"}, redis.WithPoolSize(10))
+	if err != nil {
+		// Handle error
+	}
+
+	items, err := c.GetMulti(context.Background(), "foo", "bar")
+	if err != nil {
+		// Handle error
+	}
+
+	_, _ = items[0].String()
+}
+
+func ExampleNewSentinel() {
+	c, err := redis.NewSentinel(redis.SentinelOptions{
+		MasterName:   "mymaster",
+		Addrs:        []string{"localhost:26379"},
+		ReplicaReads: true,
+	}, redis.WithReadTimeout(10*time.Millisecond))
+	if err != nil {
+		// Handle error
+	}
+
+	i := c.Get(context.Background(), "foobar")
+	if i.Err != nil {
+		// Handle error
+	}
+
+	_, _ = i.Float64()
+}
+
+func ExampleNewClientCache() {
+	r, err := redis.New("redis://localhost:6379")
+	if err != nil {
+		// Handle error
+	}
+
+	c, err := redis.NewClientCache(context.Background(), r, redis.WithClientCacheSize(1000))
+	if err != nil {
+		// Handle error
+	}
+	defer c.Close()
+
+	i := c.Get(context.Background(), "foobar")
+	if i.Err != nil {
+		// Handle error
+	}
+
+	_, _ = i.String()
+}
+
+func ExampleNewRing() {
+	c, err := redis.NewRing(redis.RingOptions{
+		Addrs: map[string]string{
+			"shard1": "localhost:6379",
+			"shard2": "localhost:6380",
+		},
+	}, redis.WithPoolSize(10))
+	if err != nil {
+		// Handle error
+	}
+
+	items, err := c.GetMulti(context.Background(), "foo", "bar")
+	if err != nil {
+		// Handle error
+	}
+
+	_, _ = items[0].String()
+}
+
+func ExampleNewWithClient() {
+	client := goredis.NewUniversalClient(&goredis.UniversalOptions{
+		Addrs: []string{"localhost:6379"},
+	})
+
+	c := redis.NewWithClient(client)
+
+	i := c.Get(context.Background(), "foobar")
+	if i.Err != nil {
+		// Handle error
+	}
+
+	_, _ = i.Float64()
+}
+
+func ExampleRedis_CompareAndSwap() {
+	c, err := redis.New("redis://localhost:6379")
+	if err != nil {
+		// Handle error
+	}
+
+	ctx := context.Background()
+	for {
+		i := c.Gets(ctx, "foobar")
+		if i.Err != nil {
+			// Handle error
+		}
+
+		n, _ := i.Int64()
+		err = c.CompareAndSwap(ctx, "foobar", n*2, i.Token, time.Hour)
+		if errors.Is(err, cache.ErrCASConflict) {
+			continue
+		}
+		if err != nil {
+			// Handle error
+		}
+		break
+	}
+}
+
+func ExampleRedis_Tx() {
+	c, err := redis.New("redis://localhost:6379")
+	if err != nil {
+		// Handle error
+	}
+
+	ctx := context.Background()
+	err = c.Tx(ctx, []string{"quota:a", "quota:b"}, func(tx *redis.Tx) error {
+		n, err := tx.Get(ctx, "quota:a").Int64()
+		if err != nil {
+			return err
+		}
+		if n < 10 {
+			return errors.New("insufficient quota")
+		}
+
+		if _, err = tx.Dec(ctx, "quota:a", 10); err != nil {
+			return err
+		}
+		_, err = tx.Inc(ctx, "quota:b", 10)
+		return err
+	})
+	if err != nil {
+		// Handle error
+	}
+}
+
+func ExampleRedis_RunScript() {
+	c, err := redis.New("redis://localhost:6379")
+	if err != nil {
+		// Handle error
+	}
+
+	c.Scripts().Register("double", `return redis.call("INCRBY", KEYS[1], redis.call("GET", KEYS[1]) or 0)`)
+
+	n, err := c.RunScript(context.Background(), "double", []string{"foobar"}).Int64()
+	if err != nil {
+		// Handle error
+	}
+
+	_ = n
+}
+
+func ExampleRedis_DeletePattern() {
+	c, err := redis.New("redis://localhost:6379")
+	if err != nil {
+		// Handle error
+	}
+
+	n, err := c.DeletePattern(context.Background(), "user:123:*", redis.DeletePatternOptions{
+		BatchSize: 500,
+		Delay:     10 * time.Millisecond,
+	})
+	if err != nil {
+		// Handle error
+	}
+
+	_ = n
+}
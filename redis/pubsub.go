@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2/invalidation"
+)
+
+// PubSubTransport is an invalidation transport using Redis Pub/Sub.
+type PubSubTransport struct {
+	conn    redis.UniversalClient
+	channel string
+}
+
+// NewPubSubTransport returns an invalidation transport publishing
+// to the given channel.
+func NewPubSubTransport(conn redis.UniversalClient, channel string) *PubSubTransport {
+	return &PubSubTransport{
+		conn:    conn,
+		channel: channel,
+	}
+}
+
+// PubSubTransport returns an invalidation transport publishing to the
+// given channel, using the connection of the cache.
+func (c Redis) PubSubTransport(channel string) *PubSubTransport {
+	return NewPubSubTransport(c.conn, channel)
+}
+
+// Publish publishes the message to all subscribers.
+func (t *PubSubTransport) Publish(ctx context.Context, msg invalidation.Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return t.conn.Publish(ctx, t.channel, b).Err()
+}
+
+// Subscribe subscribes to published messages, waiting for the
+// subscription to be confirmed.
+func (t *PubSubTransport) Subscribe(ctx context.Context) (invalidation.Subscription, error) {
+	ps := t.conn.Subscribe(ctx, t.channel)
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+	return &pubSubSubscription{ps: ps}, nil
+}
+
+type pubSubSubscription struct {
+	ps *redis.PubSub
+}
+
+// Receive waits for the next message.
+func (s *pubSubSubscription) Receive(ctx context.Context) (invalidation.Message, error) {
+	for {
+		m, err := s.ps.Receive(ctx)
+		if err != nil {
+			return invalidation.Message{}, err
+		}
+
+		msg, ok := m.(*redis.Message)
+		if !ok {
+			continue
+		}
+
+		var inv invalidation.Message
+		if err = json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+			return invalidation.Message{}, fmt.Errorf("redis: invalid invalidation message: %w", err)
+		}
+		return inv, nil
+	}
+}
+
+// Close closes the subscription.
+func (s *pubSubSubscription) Close() error {
+	return s.ps.Close()
+}
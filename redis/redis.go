@@ -3,7 +3,11 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -11,6 +15,8 @@ import (
 	"github.com/hamba/cache/v2/internal/decoder"
 )
 
+var errInvalidToken = errors.New("redis: invalid cas token")
+
 // OptsFunc represents an configuration function for Redis.
 type OptsFunc func(*redis.Options)
 
@@ -42,14 +48,120 @@ func WithWriteTimeout(timeout time.Duration) OptsFunc {
 	}
 }
 
+// WithTLSConfig configures the Redis TLS config.
+func WithTLSConfig(cfg *tls.Config) OptsFunc {
+	return func(o *redis.Options) {
+		o.TLSConfig = cfg
+	}
+}
+
+// WithRootCAs configures the Redis TLS certificate authorities used to verify the server.
+func WithRootCAs(pool *x509.CertPool) OptsFunc {
+	return func(o *redis.Options) {
+		tlsConfig(o).RootCAs = pool
+	}
+}
+
+// WithClientCert configures the Redis TLS client certificate.
+func WithClientCert(cert tls.Certificate) OptsFunc {
+	return func(o *redis.Options) {
+		cfg := tlsConfig(o)
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+}
+
+// WithServerName configures the Redis TLS server name used to verify the server.
+func WithServerName(name string) OptsFunc {
+	return func(o *redis.Options) {
+		tlsConfig(o).ServerName = name
+	}
+}
+
+// WithMinTLSVersion configures the Redis minimum TLS version.
+func WithMinTLSVersion(version uint16) OptsFunc {
+	return func(o *redis.Options) {
+		tlsConfig(o).MinVersion = version
+	}
+}
+
+// WithCredentials configures the Redis username and password.
+//
+// If username is empty, the legacy password only authentication is used.
+func WithCredentials(username, password string) OptsFunc {
+	return func(o *redis.Options) {
+		o.Username = username
+		o.Password = password
+	}
+}
+
+// CredentialsProvider returns the username and password used to authenticate.
+type CredentialsProvider func(ctx context.Context) (username, password string, err error)
+
+// WithCredentialsProvider configures a callback that provides the Redis username
+// and password for each new connection, allowing the credentials to be rotated.
+//
+// The provider replaces any configured username and password. When using
+// Sentinel, the provider only authenticates the master and replica
+// connections; the sentinels use the SentinelOptions credentials.
+func WithCredentialsProvider(fn CredentialsProvider) OptsFunc {
+	return func(o *redis.Options) {
+		// The database can only be selected once authenticated.
+		db := o.DB
+		onConnect := o.OnConnect
+
+		o.Username, o.Password, o.DB = "", "", 0
+		o.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+			username, password, err := fn(ctx)
+			if err != nil {
+				return err
+			}
+
+			if username != "" {
+				err = cn.AuthACL(ctx, username, password).Err()
+			} else {
+				err = cn.Auth(ctx, password).Err()
+			}
+			if err != nil {
+				return err
+			}
+
+			if db > 0 {
+				if err = cn.Select(ctx, db).Err(); err != nil {
+					return err
+				}
+			}
+
+			if onConnect != nil {
+				return onConnect(ctx, cn)
+			}
+			return nil
+		}
+	}
+}
+
+func tlsConfig(o *redis.Options) *tls.Config {
+	if o.TLSConfig == nil {
+		o.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return o.TLSConfig
+}
+
 // Redis is a redis adapter.
 type Redis struct {
-	conn *redis.Client
-	dec  cache.Decoder
+	conn    redis.UniversalClient
+	scripts *ScriptRegistry
+	dec     cache.Decoder
 }
 
 // New create a new Redis instance.
+//
+// A URI with the redis+sentinel scheme creates a Redis Sentinel failover
+// client, in the form redis+sentinel://[[user]:password@]host[:port][,host[:port]]/master[/db].
 func New(uri string, opts ...OptsFunc) (*Redis, error) {
+	if strings.HasPrefix(uri, sentinelScheme+"://") {
+		return newSentinelFromURL(uri, opts...)
+	}
+
 	o, err := redis.ParseURL(uri)
 	if err != nil {
 		return nil, err
@@ -61,10 +173,19 @@ func New(uri string, opts ...OptsFunc) (*Redis, error) {
 
 	c := redis.NewClient(o)
 
+	return NewWithClient(c), nil
+}
+
+// NewWithClient creates a new Redis instance using an existing client.
+//
+// Any go-redis client can be used, including single node, cluster,
+// sentinel failover and ring clients.
+func NewWithClient(client redis.UniversalClient) *Redis {
 	return &Redis{
-		conn: c,
-		dec:  decoder.StringDecoder{},
-	}, nil
+		conn:    client,
+		scripts: NewScriptRegistry(),
+		dec:     decoder.StringDecoder{},
+	}
 }
 
 // Get gets the item for the given key.
@@ -79,6 +200,13 @@ func (c Redis) Get(ctx context.Context, key string) cache.Item {
 
 // GetMulti gets the items for the given keys.
 func (c Redis) GetMulti(ctx context.Context, keys ...string) ([]cache.Item, error) {
+	switch conn := c.conn.(type) {
+	case *redis.ClusterClient:
+		return c.getMultiCluster(ctx, conn, keys)
+	case *redis.Ring:
+		return c.getMultiRing(ctx, conn, keys)
+	}
+
 	val, err := c.conn.MGet(ctx, keys...).Result()
 	if err != nil {
 		return nil, err
@@ -86,14 +214,7 @@ func (c Redis) GetMulti(ctx context.Context, keys ...string) ([]cache.Item, erro
 
 	i := []cache.Item{}
 	for _, v := range val {
-		valErr := cache.ErrCacheMiss
-		var b []byte
-		if v != nil {
-			b = []byte(v.(string))
-			valErr = nil
-		}
-
-		i = append(i, cache.NewItem(c.dec, b, valErr))
+		i = append(i, c.toItem(v))
 	}
 
 	return i, nil
@@ -125,6 +246,104 @@ func (c Redis) Delete(ctx context.Context, key string) error {
 	return c.conn.Del(ctx, key).Err()
 }
 
+// SetMulti sets the entries in the cache in a single pipeline.
+func (c Redis) SetMulti(ctx context.Context, entries ...cache.Entry) []error {
+	cmds := make([]*redis.StatusCmd, len(entries))
+	_, _ = c.conn.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, e := range entries {
+			cmds[i] = p.Set(ctx, e.Key, e.Value, e.Expire)
+		}
+		return nil
+	})
+
+	errs := make([]error, len(entries))
+	for i, cmd := range cmds {
+		errs[i] = cmd.Err()
+	}
+	return errs
+}
+
+// DeleteMulti deletes the items with the given keys in a single pipeline.
+func (c Redis) DeleteMulti(ctx context.Context, keys ...string) []error {
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, _ = c.conn.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, k := range keys {
+			cmds[i] = p.Del(ctx, k)
+		}
+		return nil
+	})
+
+	errs := make([]error, len(keys))
+	for i, cmd := range cmds {
+		errs[i] = cmd.Err()
+	}
+	return errs
+}
+
+// ExecBatch executes the operations in a single pipeline, returning
+// an item for each operation in order.
+func (c Redis) ExecBatch(ctx context.Context, ops []cache.BatchOp) []cache.Item {
+	cmds := make([]redis.Cmder, len(ops))
+	_, _ = c.conn.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, op := range ops {
+			cmds[i] = queueOp(ctx, p, op)
+		}
+		return nil
+	})
+
+	items := make([]cache.Item, len(ops))
+	for i, cmd := range cmds {
+		items[i] = c.cmdItem(ops[i], cmd)
+	}
+	return items
+}
+
+func queueOp(ctx context.Context, p redis.Pipeliner, op cache.BatchOp) redis.Cmder {
+	switch op.Type {
+	case cache.BatchGet:
+		return p.Get(ctx, op.Key)
+	case cache.BatchSet:
+		return p.Set(ctx, op.Key, op.Value, op.Expire)
+	case cache.BatchAdd:
+		return p.SetNX(ctx, op.Key, op.Value, op.Expire)
+	case cache.BatchReplace:
+		return p.SetXX(ctx, op.Key, op.Value, op.Expire)
+	case cache.BatchDelete:
+		return p.Del(ctx, op.Key)
+	case cache.BatchInc:
+		return p.IncrBy(ctx, op.Key, int64(op.Delta))
+	case cache.BatchDec:
+		return p.DecrBy(ctx, op.Key, int64(op.Delta))
+	default:
+		cmd := redis.NewStatusCmd(ctx)
+		cmd.SetErr(errors.New("redis: unknown batch operation"))
+		return cmd
+	}
+}
+
+func (c Redis) cmdItem(op cache.BatchOp, cmd redis.Cmder) cache.Item {
+	if err := cmd.Err(); err != nil {
+		if errors.Is(err, redis.Nil) {
+			err = cache.ErrCacheMiss
+		}
+		return cache.NewItem(c.dec, []byte(nil), err)
+	}
+
+	switch op.Type {
+	case cache.BatchGet:
+		b, _ := cmd.(*redis.StringCmd).Bytes()
+		return cache.NewItem(c.dec, b, nil)
+	case cache.BatchAdd, cache.BatchReplace:
+		if !cmd.(*redis.BoolCmd).Val() {
+			return cache.NewItem(c.dec, []byte(nil), cache.ErrNotStored)
+		}
+	case cache.BatchInc, cache.BatchDec:
+		n := cmd.(*redis.IntCmd).Val()
+		return cache.NewItem(c.dec, []byte(strconv.FormatInt(n, 10)), nil)
+	}
+	return cache.NewItem(c.dec, []byte(nil), nil)
+}
+
 // Inc increments a key by the value.
 func (c Redis) Inc(ctx context.Context, key string, value uint64) (int64, error) {
 	return c.conn.IncrBy(ctx, key, int64(value)).Result()
@@ -134,3 +353,135 @@ func (c Redis) Inc(ctx context.Context, key string, value uint64) (int64, error)
 func (c Redis) Dec(ctx context.Context, key string, value uint64) (int64, error) {
 	return c.conn.DecrBy(ctx, key, int64(value)).Result()
 }
+
+// IncFloat increments a key by the floating-point value.
+func (c Redis) IncFloat(ctx context.Context, key string, value float64) (float64, error) {
+	return c.conn.IncrByFloat(ctx, key, value).Result()
+}
+
+// Ping checks that the servers are reachable. In a cluster each master
+// is pinged, and in a ring each shard.
+func (c Redis) Ping(ctx context.Context) error {
+	return c.eachNode(ctx, func(node redis.Cmdable) error {
+		return node.Ping(ctx).Err()
+	})
+}
+
+// Flush removes all keys from the database using FLUSHDB. In a cluster
+// each master is flushed, and in a ring each shard.
+func (c Redis) Flush(ctx context.Context) error {
+	return c.eachNode(ctx, func(node redis.Cmdable) error {
+		return node.FlushDB(ctx).Err()
+	})
+}
+
+// Close closes the client, releasing its connections.
+func (c Redis) Close() error {
+	return c.conn.Close()
+}
+
+func (c Redis) eachNode(ctx context.Context, fn func(redis.Cmdable) error) error {
+	nodes, err := c.scanNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err = fn(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// casToken is the value of an item when it was read.
+type casToken string
+
+// Gets gets the item for the given key with its compare and swap token.
+//
+// The token is the value of the item, so a value that changes and is
+// changed back is not detected as a conflict.
+func (c Redis) Gets(ctx context.Context, key string) cache.CASItem {
+	b, err := c.conn.Get(ctx, key).Bytes()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return cache.CASItem{Item: cache.NewItem(c.dec, b, cache.ErrCacheMiss)}
+	case err != nil:
+		return cache.CASItem{Item: cache.NewItem(c.dec, b, err)}
+	}
+
+	return cache.CASItem{Item: cache.NewItem(c.dec, b, nil), Token: casToken(b)}
+}
+
+// CompareAndSwap sets the item in the cache, but only if it has not been
+// modified since the token was read.
+func (c Redis) CompareAndSwap(
+	ctx context.Context,
+	key string,
+	value interface{},
+	token cache.CASToken,
+	expire time.Duration,
+) error {
+	t, ok := token.(casToken)
+	if !ok {
+		return errInvalidToken
+	}
+
+	res, err := c.scripts.run(ctx, c.conn, casScript, []string{key}, string(t), value, expire.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	switch res {
+	case -1:
+		return cache.ErrCacheMiss
+	case 0:
+		return cache.ErrCASConflict
+	}
+	return nil
+}
+
+// Touch updates the expiration of the item with the given key.
+func (c Redis) Touch(ctx context.Context, key string, expire time.Duration) error {
+	res, err := c.scripts.run(ctx, c.conn, touchScript, []string{key}, expire.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return cache.ErrCacheMiss
+	}
+	return nil
+}
+
+// GetAndTouch gets the item for the given key and updates its expiration.
+func (c Redis) GetAndTouch(ctx context.Context, key string, expire time.Duration) cache.Item {
+	b, err := c.conn.GetEx(ctx, key, expire).Bytes()
+	if errors.Is(err, redis.Nil) {
+		err = cache.ErrCacheMiss
+	}
+
+	return cache.NewItem(c.dec, b, err)
+}
+
+// TTL returns the remaining lifetime of the item with the given key.
+func (c Redis) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := c.conn.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	switch ttl {
+	case -2:
+		return 0, cache.ErrCacheMiss
+	case -1:
+		return cache.NoExpiration, nil
+	}
+	return ttl, nil
+}
+
+func (c Redis) toItem(v interface{}) cache.Item {
+	if v == nil {
+		return cache.NewItem(c.dec, []byte(nil), cache.ErrCacheMiss)
+	}
+	return cache.NewItem(c.dec, []byte(v.(string)), nil)
+}
@@ -1,11 +1,15 @@
 package redis
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"testing"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWithPoolSize(t *testing.T) {
@@ -44,10 +48,88 @@ func TestNewRedis(t *testing.T) {
 	c, err := New("redis://test", WithPoolSize(12))
 
 	assert.NoError(t, err)
-	assert.Equal(t, 12, c.conn.Options().PoolSize)
+	assert.Equal(t, 12, c.conn.(*redis.Client).Options().PoolSize)
 }
 
 func TestNewRedis_InvalidUri(t *testing.T) {
 	_, err := New("test")
 	assert.Error(t, err)
 }
+
+func TestNewWithClient(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "test:6379"})
+
+	c := NewWithClient(client)
+
+	assert.Same(t, client, c.conn)
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	o := &redis.Options{}
+	cfg := &tls.Config{ServerName: "test"}
+
+	WithTLSConfig(cfg)(o)
+
+	assert.Same(t, cfg, o.TLSConfig)
+}
+
+func TestWithRootCAs(t *testing.T) {
+	o := &redis.Options{}
+	pool := x509.NewCertPool()
+
+	WithRootCAs(pool)(o)
+
+	require.NotNil(t, o.TLSConfig)
+	assert.Same(t, pool, o.TLSConfig.RootCAs)
+	assert.Equal(t, uint16(tls.VersionTLS12), o.TLSConfig.MinVersion)
+}
+
+func TestWithClientCert(t *testing.T) {
+	o := &redis.Options{}
+	cert := tls.Certificate{Certificate: [][]byte{[]byte("test")}}
+
+	WithClientCert(cert)(o)
+
+	require.NotNil(t, o.TLSConfig)
+	assert.Equal(t, []tls.Certificate{cert}, o.TLSConfig.Certificates)
+}
+
+func TestWithServerName(t *testing.T) {
+	o := &redis.Options{}
+
+	WithServerName("test")(o)
+
+	require.NotNil(t, o.TLSConfig)
+	assert.Equal(t, "test", o.TLSConfig.ServerName)
+}
+
+func TestWithMinTLSVersion(t *testing.T) {
+	o := &redis.Options{}
+
+	WithMinTLSVersion(tls.VersionTLS13)(o)
+
+	require.NotNil(t, o.TLSConfig)
+	assert.Equal(t, uint16(tls.VersionTLS13), o.TLSConfig.MinVersion)
+}
+
+func TestWithCredentials(t *testing.T) {
+	o := &redis.Options{}
+
+	WithCredentials("user", "pass")(o)
+
+	assert.Equal(t, "user", o.Username)
+	assert.Equal(t, "pass", o.Password)
+}
+
+func TestWithCredentialsProvider(t *testing.T) {
+	o := &redis.Options{Username: "user", Password: "pass", DB: 2}
+
+	WithCredentialsProvider(func(context.Context) (string, string, error) {
+		return "user", "pass", nil
+	})(o)
+
+	assert.Empty(t, o.Username)
+	assert.Empty(t, o.Password)
+	assert.Equal(t, 0, o.DB)
+	assert.NotNil(t, o.OnConnect)
+}
@@ -4,8 +4,14 @@ import (
 	"context"
 	"net"
 	"testing"
+	"time"
 
+	goredis "github.com/go-redis/redis/v8"
 	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/cachetest"
+	"github.com/hamba/cache/v2/invalidation"
+	"github.com/hamba/cache/v2/lock"
+	"github.com/hamba/cache/v2/ratelimit"
 	"github.com/hamba/cache/v2/redis"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -94,3 +100,703 @@ func TestRedisCache(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, int64(0), i)
 }
+
+func TestRedisRingCache_GetMulti(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.NewRing(redis.RingOptions{
+		Addrs: map[string]string{"shard1": testRedisServer},
+	}, func(o *goredis.Options) {
+		o.DB = 1
+	})
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "ring1", "foo", 0)
+	require.NoError(t, err)
+	err = c.Set(ctx, "ring2", "bar", 0)
+	require.NoError(t, err)
+
+	v, err := c.GetMulti(ctx, "ring2", "_", "ring1")
+	require.NoError(t, err)
+	require.Len(t, v, 3)
+	str, err := v[0].String()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", str)
+	assert.EqualError(t, v[1].Err, "cache: miss")
+	str, err = v[2].String()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", str)
+}
+
+func TestRedisCache_CompareAndSwap(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.CASCache)(nil), c)
+
+	err = c.Set(ctx, "cas", "foo", 0)
+	require.NoError(t, err)
+
+	got := c.Gets(ctx, "cas")
+	require.NoError(t, got.Err)
+
+	err = c.CompareAndSwap(ctx, "cas", "bar", got.Token, time.Minute)
+	require.NoError(t, err)
+
+	err = c.CompareAndSwap(ctx, "cas", "baz", got.Token, 0)
+	assert.ErrorIs(t, err, cache.ErrCASConflict)
+
+	str, err := c.Get(ctx, "cas").String()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", str)
+
+	got = c.Gets(ctx, "cas")
+	require.NoError(t, got.Err)
+	err = c.Delete(ctx, "cas")
+	require.NoError(t, err)
+
+	err = c.CompareAndSwap(ctx, "cas", "baz", got.Token, 0)
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	got = c.Gets(ctx, "cas")
+	assert.ErrorIs(t, got.Err, cache.ErrCacheMiss)
+	assert.Nil(t, got.Token)
+}
+
+func TestRedisCache_CompareAndSwapInvalidToken(t *testing.T) {
+	c := redis.NewWithClient(goredis.NewClient(&goredis.Options{Addr: testRedisServer}))
+
+	err := c.CompareAndSwap(context.Background(), "cas", "bar", 1, 0)
+
+	assert.Error(t, err)
+}
+
+func TestRedisCache_Touch(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.TouchCache)(nil), c)
+	assert.Implements(t, (*cache.TTLCache)(nil), c)
+
+	err = c.Set(ctx, "touch", "foobar", time.Minute)
+	require.NoError(t, err)
+
+	err = c.Touch(ctx, "touch", time.Hour)
+	require.NoError(t, err)
+
+	ttl, err := c.TTL(ctx, "touch")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second))
+
+	err = c.Touch(ctx, "touch", 0)
+	require.NoError(t, err)
+
+	ttl, err = c.TTL(ctx, "touch")
+	require.NoError(t, err)
+	assert.Equal(t, cache.NoExpiration, ttl)
+
+	err = c.Touch(ctx, "_", time.Hour)
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	str, err := c.GetAndTouch(ctx, "touch", time.Minute).String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+
+	ttl, err = c.TTL(ctx, "touch")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	_, err = c.GetAndTouch(ctx, "_", time.Minute).String()
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	_, err = c.TTL(ctx, "_")
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestRedisCache_Multi(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.MultiCache)(nil), c)
+
+	errs := c.SetMulti(ctx,
+		cache.Entry{Key: "multi1", Value: "foo"},
+		cache.Entry{Key: "multi2", Value: 2, Expire: time.Minute},
+	)
+	assert.Equal(t, []error{nil, nil}, errs)
+
+	v, err := c.GetMulti(ctx, "multi1", "multi2")
+	require.NoError(t, err)
+	str, err := v[0].String()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", str)
+	i, err := v[1].Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), i)
+
+	ttl, err := c.TTL(ctx, "multi2")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	errs = c.DeleteMulti(ctx, "multi1", "multi2")
+	assert.Equal(t, []error{nil, nil}, errs)
+
+	v, err = c.GetMulti(ctx, "multi1", "multi2")
+	require.NoError(t, err)
+	assert.ErrorIs(t, v[0].Err, cache.ErrCacheMiss)
+	assert.ErrorIs(t, v[1].Err, cache.ErrCacheMiss)
+}
+
+func TestRedisCache_ExecBatch(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.BatchCache)(nil), c)
+
+	_ = c.Delete(ctx, "batch-counter")
+	err = c.Set(ctx, "batch-test", "foo", 0)
+	require.NoError(t, err)
+
+	b := cache.NewBatch()
+	inc := b.Inc("batch-counter", 2)
+	set := b.Set("batch-marker", "bar", time.Minute)
+	add := b.Add("batch-test", "baz", 0)
+	get := b.Get("batch-marker")
+	miss := b.Get("_")
+	dec := b.Dec("batch-counter", 1)
+	del := b.Delete("batch-test")
+	rep := b.Replace("batch-test", "baz", 0)
+
+	items := b.Exec(ctx, c)
+
+	require.Len(t, items, b.Len())
+	i, err := items[inc].Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), i)
+	assert.NoError(t, items[set].Err)
+	assert.ErrorIs(t, items[add].Err, cache.ErrNotStored)
+	str, err := items[get].String()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", str)
+	assert.ErrorIs(t, items[miss].Err, cache.ErrCacheMiss)
+	i, err = items[dec].Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), i)
+	assert.NoError(t, items[del].Err)
+	assert.ErrorIs(t, items[rep].Err, cache.ErrNotStored)
+}
+
+func TestRedisCache_Tx(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "tx-from", 10, 0)
+	require.NoError(t, err)
+	_ = c.Delete(ctx, "tx-to")
+
+	err = c.Tx(ctx, []string{"tx-from", "tx-to"}, func(tx *redis.Tx) error {
+		n, err := tx.Dec(ctx, "tx-from", 3)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, int64(7), n)
+
+		if _, err = tx.Inc(ctx, "tx-to", 3); err != nil {
+			return err
+		}
+
+		err = tx.Add(ctx, "tx-to", "foo", 0)
+		assert.ErrorIs(t, err, cache.ErrNotStored)
+
+		i, err := tx.Get(ctx, "tx-to").Int64()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), i)
+		return nil
+	})
+	require.NoError(t, err)
+
+	v, err := c.GetMulti(ctx, "tx-from", "tx-to")
+	require.NoError(t, err)
+	i, err := v[0].Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), i)
+	i, err = v[1].Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), i)
+}
+
+func TestRedisCache_TxRetriesOnConflict(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "tx-conflict", 1, 0)
+	require.NoError(t, err)
+
+	attempts := 0
+	err = c.Tx(ctx, []string{"tx-conflict"}, func(tx *redis.Tx) error {
+		attempts++
+		n, err := tx.Get(ctx, "tx-conflict").Int64()
+		if err != nil {
+			return err
+		}
+		if attempts == 1 {
+			// Modify the watched key outside the transaction.
+			require.NoError(t, c.Set(ctx, "tx-conflict", 5, 0))
+		}
+		return tx.Set(ctx, "tx-conflict", n*2, 0)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, attempts)
+	i, err := c.Get(ctx, "tx-conflict").Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), i)
+}
+
+func TestRedisCache_Scripts(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	c.Scripts().Register("double", `return redis.call("INCRBY", KEYS[1], redis.call("GET", KEYS[1]))`)
+	err = c.LoadScripts(ctx)
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "script", 2, 0)
+	require.NoError(t, err)
+
+	n, err := c.RunScript(ctx, "double", []string{"script"}).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+}
+
+func TestRedisCache_GetAndDelete(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "getdel", "foo", 0)
+	require.NoError(t, err)
+
+	str, err := c.GetAndDelete(ctx, "getdel").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", str)
+
+	_, err = c.GetAndDelete(ctx, "getdel").String()
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestRedisCache_SetIfEqual(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "setifequal", 1, 0)
+	require.NoError(t, err)
+
+	err = c.SetIfEqual(ctx, "setifequal", 1, 2, 0)
+	require.NoError(t, err)
+
+	err = c.SetIfEqual(ctx, "setifequal", 1, 3, 0)
+	assert.ErrorIs(t, err, cache.ErrNotStored)
+
+	i, err := c.Get(ctx, "setifequal").Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), i)
+
+	err = c.SetIfEqual(ctx, "_", 1, 3, 0)
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestRedisCache_DeleteIfEqual(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	assert.Implements(t, (*lock.Cache)(nil), c)
+
+	err = c.Set(ctx, "delifequal", 1, 0)
+	require.NoError(t, err)
+
+	err = c.DeleteIfEqual(ctx, "delifequal", 2)
+	assert.ErrorIs(t, err, cache.ErrNotStored)
+
+	err = c.DeleteIfEqual(ctx, "delifequal", 1)
+	require.NoError(t, err)
+
+	err = c.DeleteIfEqual(ctx, "delifequal", 1)
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestRedisCache_IncWithCeiling(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	_ = c.Delete(ctx, "ceiling")
+
+	n, err := c.IncWithCeiling(ctx, "ceiling", 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	n, err = c.IncWithCeiling(ctx, "ceiling", 2, 3)
+	assert.ErrorIs(t, err, cache.ErrNotStored)
+	assert.Equal(t, int64(2), n)
+
+	n, err = c.IncWithCeiling(ctx, "ceiling", 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
+
+func TestRedisCache_IncWithExpiry(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	_ = c.Delete(ctx, "incexpiry")
+
+	n, err := c.IncWithExpiry(ctx, "incexpiry", 2, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	err = c.Touch(ctx, "incexpiry", time.Hour)
+	require.NoError(t, err)
+
+	n, err = c.IncWithExpiry(ctx, "incexpiry", 2, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+
+	ttl, err := c.TTL(ctx, "incexpiry")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second))
+}
+
+func TestRedisCache_Counter(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	cachetest.Counter(t, c)
+}
+
+func TestRedisCache_Float(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	cachetest.Float(t, c)
+}
+
+func TestRedisCache_TakeTokens(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	assert.Implements(t, (*ratelimit.BucketCache)(nil), c)
+
+	_ = c.Delete(ctx, "bucket")
+
+	tokens, ok, err := c.TakeTokens(ctx, "bucket", 1, 2, 2, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, float64(0), tokens)
+
+	tokens, ok, err = c.TakeTokens(ctx, "bucket", 1, 2, 1, now.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0.5, tokens)
+
+	tokens, ok, err = c.TakeTokens(ctx, "bucket", 1, 2, 1, now.Add(1500*time.Millisecond))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, tokens)
+
+	ttl, err := c.TTL(ctx, "bucket")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+}
+
+func TestRedisCache_PubSubTransport(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	tr := c.PubSubTransport("invalidation")
+
+	sub, err := tr.Subscribe(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sub.Close() })
+
+	want := invalidation.Message{Source: "test", Seq: 1, Keys: []string{"a", "b"}}
+	err = tr.Publish(ctx, want)
+	require.NoError(t, err)
+
+	recvCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	got, err := sub.Receive(recvCtx)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestRedisCache_ClientCache(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	r, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+	other, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	c, err := redis.NewClientCache(ctx, r)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	assert.Implements(t, (*cache.Cache)(nil), c)
+
+	err = c.Set(ctx, "tracked", "foo", 0)
+	require.NoError(t, err)
+
+	str, err := c.Get(ctx, "tracked").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", str)
+
+	err = other.Set(ctx, "tracked", "bar", 0)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		str, err = c.Get(ctx, "tracked").String()
+		return err == nil && str == "bar"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRedisCache_ClientCacheReadsOwnWrites(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	r, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	c, err := redis.NewClientCache(ctx, r)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	err = c.Set(ctx, "own", "foo", 0)
+	require.NoError(t, err)
+	_ = c.Get(ctx, "own")
+
+	errs := c.SetMulti(ctx, cache.Entry{Key: "own", Value: "bar"})
+	require.NoError(t, errs[0])
+
+	str, err := c.Get(ctx, "own").String()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", str)
+
+	_ = c.ExecBatch(ctx, []cache.BatchOp{{Type: cache.BatchSet, Key: "own", Value: "baz"}})
+
+	str, err = c.Get(ctx, "own").String()
+	require.NoError(t, err)
+	assert.Equal(t, "baz", str)
+}
+
+func TestRedisCache_Scan(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.Scanner)(nil), c)
+
+	for _, k := range []string{"scan:1", "scan:2", "scan:3"} {
+		err = c.Set(ctx, k, "foo", 0)
+		require.NoError(t, err)
+	}
+
+	keys := map[string]bool{}
+	err = c.Scan(ctx, "scan:*", cache.ScanOptions{BatchSize: 1}, func(key string) error {
+		keys[key] = true
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"scan:1": true, "scan:2": true, "scan:3": true}, keys)
+}
+
+func TestRedisCache_DeletePattern(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	for _, k := range []string{"delpattern:1", "delpattern:2", "delpattern:3", "keep:1"} {
+		err = c.Set(ctx, k, "foo", 0)
+		require.NoError(t, err)
+	}
+
+	n, err := c.DeletePattern(ctx, "delpattern:*", redis.DeletePatternOptions{BatchSize: 2, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, c.Get(ctx, "delpattern:1").Err)
+
+	n, err = c.DeletePattern(ctx, "delpattern:*", redis.DeletePatternOptions{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.ErrorIs(t, c.Get(ctx, "delpattern:1").Err, cache.ErrCacheMiss)
+	assert.NoError(t, c.Get(ctx, "keep:1").Err)
+}
+
+func TestRedisCache_Lifecycle(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.Pinger)(nil), c)
+	assert.Implements(t, (*cache.Flusher)(nil), c)
+	assert.Implements(t, (*cache.Closer)(nil), c)
+
+	err = c.Ping(ctx)
+	require.NoError(t, err)
+
+	err = c.Set(ctx, "flushed", "foo", 0)
+	require.NoError(t, err)
+
+	err = c.Flush(ctx)
+	require.NoError(t, err)
+	assert.ErrorIs(t, c.Get(ctx, "flushed").Err, cache.ErrCacheMiss)
+
+	err = c.Close()
+	require.NoError(t, err)
+
+	err = c.Ping(ctx)
+	assert.Error(t, err)
+}
+
+func TestRedisCache_Stats(t *testing.T) {
+	if skipRedis {
+		t.Skipf("skipping test; no running server at %s", testRedisServer)
+	}
+
+	ctx := context.Background()
+
+	c, err := redis.New("redis://" + testRedisServer + "/1")
+	require.NoError(t, err)
+
+	assert.Implements(t, (*cache.StatsCache)(nil), c)
+
+	err = c.Set(ctx, "stats", "foo", 0)
+	require.NoError(t, err)
+
+	stats, err := c.Stats(ctx)
+
+	require.NoError(t, err)
+	assert.Greater(t, stats.Items, int64(0))
+	assert.Greater(t, stats.Bytes, int64(0))
+	assert.Greater(t, stats.Connections, int64(0))
+	assert.Greater(t, stats.Pool.TotalConns, 0)
+}
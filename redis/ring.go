@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2"
+)
+
+// RingOptions configures the shards of a Redis ring.
+type RingOptions struct {
+	// Addrs is a map of shard name to address. Keys are distributed
+	// across the shards using rendezvous hashing on the shard names.
+	Addrs map[string]string
+
+	// HeartbeatFrequency is the frequency shards are pinged to check
+	// their availability. A shard is considered down after 3 consecutive
+	// failed checks, and its keys are rebalanced across the remaining shards
+	// until it is back up. Defaults to 500ms.
+	HeartbeatFrequency time.Duration
+}
+
+// NewRing creates a new Redis instance distributing keys across
+// independent Redis shards using client-side consistent hashing.
+//
+// The options are applied to each shard in the ring.
+func NewRing(ring RingOptions, opts ...OptsFunc) (*Redis, error) {
+	if len(ring.Addrs) == 0 {
+		return nil, errors.New("redis: at least one ring shard is required")
+	}
+
+	o := &redis.Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	c := redis.NewRing(ringOptions(ring, o))
+
+	return NewWithClient(c), nil
+}
+
+func ringOptions(ring RingOptions, o *redis.Options) *redis.RingOptions {
+	return &redis.RingOptions{
+		Addrs:              ring.Addrs,
+		HeartbeatFrequency: ring.HeartbeatFrequency,
+		Dialer:             o.Dialer,
+		OnConnect:          o.OnConnect,
+		Username:           o.Username,
+		Password:           o.Password,
+		DB:                 o.DB,
+		MaxRetries:         o.MaxRetries,
+		MinRetryBackoff:    o.MinRetryBackoff,
+		MaxRetryBackoff:    o.MaxRetryBackoff,
+		DialTimeout:        o.DialTimeout,
+		ReadTimeout:        o.ReadTimeout,
+		WriteTimeout:       o.WriteTimeout,
+		PoolFIFO:           o.PoolFIFO,
+		PoolSize:           o.PoolSize,
+		MinIdleConns:       o.MinIdleConns,
+		MaxConnAge:         o.MaxConnAge,
+		PoolTimeout:        o.PoolTimeout,
+		IdleTimeout:        o.IdleTimeout,
+		IdleCheckFrequency: o.IdleCheckFrequency,
+		TLSConfig:          o.TLSConfig,
+		Limiter:            o.Limiter,
+	}
+}
+
+// getMultiRing gets the items for the given keys from a ring.
+//
+// A MGET is only sent to the shard of its first key, so a GET is
+// pipelined per key instead. The ring pipeline sends the commands
+// to each shard concurrently.
+func (c Redis) getMultiRing(ctx context.Context, conn *redis.Ring, keys []string) ([]cache.Item, error) {
+	pipe := conn.Pipeline()
+	cmds := make([]*redis.StringCmd, 0, len(keys))
+	for _, k := range keys {
+		cmds = append(cmds, pipe.Get(ctx, k))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	items := make([]cache.Item, 0, len(keys))
+	for _, cmd := range cmds {
+		b, err := cmd.Bytes()
+		switch {
+		case errors.Is(err, redis.Nil):
+			err = cache.ErrCacheMiss
+		case err != nil:
+			return nil, err
+		}
+
+		items = append(items, cache.NewItem(c.dec, b, err))
+	}
+
+	return items, nil
+}
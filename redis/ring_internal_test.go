@@ -0,0 +1,30 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRing(t *testing.T) {
+	c, err := NewRing(RingOptions{
+		Addrs:              map[string]string{"shard1": "test:6379", "shard2": "test:6380"},
+		HeartbeatFrequency: time.Second,
+	}, WithPoolSize(12))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.conn.Close() })
+
+	o := c.conn.(*redis.Ring).Options()
+	assert.Equal(t, map[string]string{"shard1": "test:6379", "shard2": "test:6380"}, o.Addrs)
+	assert.Equal(t, time.Second, o.HeartbeatFrequency)
+	assert.Equal(t, 12, o.PoolSize)
+}
+
+func TestNewRing_NoAddrs(t *testing.T) {
+	_, err := NewRing(RingOptions{})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,172 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/internal/scan"
+)
+
+// Scan calls fn with each key matching the pattern, using SCAN.
+//
+// The batch size is passed as the SCAN count. In a cluster each master
+// is scanned in turn, and in a ring each shard.
+func (c Redis) Scan(ctx context.Context, pattern string, opts cache.ScanOptions, fn func(key string) error) error {
+	return c.scanBatches(ctx, pattern, opts, func(_ redis.Cmdable, keys []string) error {
+		for _, k := range keys {
+			if err := fn(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeletePatternOptions configures a pattern delete.
+type DeletePatternOptions struct {
+	// BatchSize is the number of keys scanned and unlinked at a time.
+	// It is a hint, and defaults to 100.
+	BatchSize int
+
+	// Delay is the pause between batches, limiting the load on the server.
+	Delay time.Duration
+
+	// DryRun counts the matching keys without deleting them.
+	DryRun bool
+}
+
+// DeletePattern deletes the keys matching the pattern, returning the
+// number of deleted keys.
+//
+// The keys are scanned in batches, and each batch is removed with UNLINK,
+// freeing the memory in the background. Keys added during the delete may
+// not be deleted. In a dry run the matching keys are counted, and a key may
+// be counted more than once.
+func (c Redis) DeletePattern(ctx context.Context, pattern string, opts DeletePatternOptions) (int64, error) {
+	scanOpts := cache.ScanOptions{BatchSize: opts.BatchSize, Delay: opts.Delay}
+
+	var n int64
+	err := c.scanBatches(ctx, pattern, scanOpts, func(node redis.Cmdable, keys []string) error {
+		if opts.DryRun {
+			n += int64(len(keys))
+			return nil
+		}
+
+		deleted, err := c.unlink(ctx, node, keys)
+		n += deleted
+		return err
+	})
+	return n, err
+}
+
+// unlink unlinks the keys from the node holding them.
+//
+// Redis Cluster rejects UNLINK across hash slots, so in a cluster the
+// keys are grouped by slot and an UNLINK is pipelined per slot.
+func (c Redis) unlink(ctx context.Context, node redis.Cmdable, keys []string) (int64, error) {
+	conn, ok := c.conn.(*redis.ClusterClient)
+	if !ok {
+		return node.Unlink(ctx, keys...).Result()
+	}
+
+	groups := map[int][]string{}
+	for _, k := range keys {
+		s := hashSlot(k)
+		groups[s] = append(groups[s], k)
+	}
+
+	pipe := conn.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(groups))
+	for _, ks := range groups {
+		cmds = append(cmds, pipe.Unlink(ctx, ks...))
+	}
+	_, err := pipe.Exec(ctx)
+
+	var n int64
+	for _, cmd := range cmds {
+		n += cmd.Val()
+	}
+	return n, err
+}
+
+// scanBatches calls fn with each batch of keys matching the pattern,
+// and the node holding them.
+func (c Redis) scanBatches(
+	ctx context.Context,
+	pattern string,
+	opts cache.ScanOptions,
+	fn func(redis.Cmdable, []string) error,
+) error {
+	nodes, err := c.scanNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err = scanNode(ctx, node, pattern, opts, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanNodes returns the nodes holding the keys: the masters of a
+// cluster, the shards of a ring, or the client itself.
+func (c Redis) scanNodes(ctx context.Context) ([]redis.Cmdable, error) {
+	var (
+		mu    sync.Mutex
+		nodes []redis.Cmdable
+	)
+	addNode := func(_ context.Context, client *redis.Client) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		nodes = append(nodes, client)
+		return nil
+	}
+
+	var err error
+	switch conn := c.conn.(type) {
+	case *redis.ClusterClient:
+		err = conn.ForEachMaster(ctx, addNode)
+	case *redis.Ring:
+		err = conn.ForEachShard(ctx, addNode)
+	default:
+		nodes = append(nodes, c.conn)
+	}
+	return nodes, err
+}
+
+func scanNode(
+	ctx context.Context,
+	node redis.Cmdable,
+	pattern string,
+	opts cache.ScanOptions,
+	fn func(redis.Cmdable, []string) error,
+) error {
+	count := int64(scan.BatchSize(opts))
+
+	var cursor uint64
+	for {
+		keys, next, err := node.Scan(ctx, cursor, pattern, count).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err = fn(node, keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+
+		if err = scan.Wait(ctx, opts.Delay); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2"
+)
+
+// Built-in script names.
+const (
+	casScript         = "cache:cas"
+	touchScript       = "cache:touch"
+	getDelScript      = "cache:getdel"
+	incrCeilingScript = "cache:incrceiling"
+	incrExpireScript  = "cache:increxpire"
+	counterScript     = "cache:counter"
+	bucketScript      = "cache:bucket"
+	delEqualScript    = "cache:delequal"
+)
+
+var builtinScripts = map[string]string{
+	// casScript sets the key to the new value if its value is unchanged. It
+	// returns -1 if the key does not exist, and 0 if the value has changed.
+	casScript: `
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return -1
+end
+if v ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`,
+
+	// touchScript updates the expiration of the key, removing it if the
+	// expiration is zero. It returns 0 if the key does not exist.
+	touchScript: `
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+if tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+else
+	redis.call("PERSIST", KEYS[1])
+end
+return 1
+`,
+
+	// delEqualScript deletes the key if its value is unchanged. It returns
+	// -1 if the key does not exist, and 0 if the value has changed.
+	delEqualScript: `
+local v = redis.call("GET", KEYS[1])
+if not v then
+	return -1
+end
+if v ~= ARGV[1] then
+	return 0
+end
+redis.call("DEL", KEYS[1])
+return 1
+`,
+
+	// getDelScript gets the value of the key and deletes it.
+	getDelScript: `
+local v = redis.call("GET", KEYS[1])
+if v then
+	redis.call("DEL", KEYS[1])
+end
+return v
+`,
+
+	// incrCeilingScript increments the key, unless the result would exceed
+	// the ceiling. It returns the value, and 1 if the key was incremented.
+	incrCeilingScript: `
+local n = tonumber(redis.call("GET", KEYS[1]) or "0")
+if n + tonumber(ARGV[1]) > tonumber(ARGV[2]) then
+	return {n, 0}
+end
+return {redis.call("INCRBY", KEYS[1], ARGV[1]), 1}
+`,
+
+	// incrExpireScript increments the key, setting the expiration if
+	// the key was created.
+	incrExpireScript: `
+local created = redis.call("EXISTS", KEYS[1]) == 0
+local n = redis.call("INCRBY", KEYS[1], ARGV[1])
+if created and tonumber(ARGV[2]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return n
+`,
+
+	// counterScript adds the delta to the key, creating it from the initial
+	// value with the expiration if it does not exist. A result below zero is
+	// clamped, unless rejecting, in which case the value is left unchanged.
+	// It returns the value, and 1 if the delta was applied.
+	counterScript: `
+local function create(n)
+	if tonumber(ARGV[3]) > 0 then
+		redis.call("SET", KEYS[1], n, "PX", ARGV[3])
+	else
+		redis.call("SET", KEYS[1], n)
+	end
+end
+local v = redis.call("GET", KEYS[1])
+local n = tonumber(ARGV[2])
+if v then
+	n = tonumber(v)
+	if not n then
+		return redis.error_reply("ERR value is not an integer or out of range")
+	end
+end
+local r = n + tonumber(ARGV[1])
+if r < 0 then
+	if ARGV[4] == "1" then
+		if not v then
+			create(n)
+		end
+		return {n, 0}
+	end
+	r = 0
+end
+if not v then
+	create(r)
+elseif r ~= n then
+	redis.call("INCRBY", KEYS[1], r - n)
+end
+return {r, 1}
+`,
+
+	// bucketScript refills the token bucket at the rate per second up to
+	// the burst, and takes the tokens if available. It returns the tokens
+	// left in the bucket, and 1 if the tokens were taken.
+	bucketScript: `
+local rate, burst, n, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+if now > last then
+	tokens = math.min(burst, tokens + (now - last) / 1000 * rate)
+	last = now
+end
+local ok = 0
+if tokens >= n then
+	tokens = tokens - n
+	ok = 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(last))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {tostring(tokens), ok}
+`,
+}
+
+// ScriptRegistry is a registry of named Lua scripts.
+//
+// Scripts are run by SHA using EVALSHA, falling back to EVAL when
+// the script is not loaded on the server.
+type ScriptRegistry struct {
+	mu      sync.RWMutex
+	scripts map[string]*redis.Script
+}
+
+// NewScriptRegistry returns a registry containing the built-in scripts.
+func NewScriptRegistry() *ScriptRegistry {
+	r := &ScriptRegistry{scripts: make(map[string]*redis.Script, len(builtinScripts))}
+	for name, src := range builtinScripts {
+		r.Register(name, src)
+	}
+	return r
+}
+
+// Register registers the script source with the given name, replacing
+// any script with the same name.
+func (r *ScriptRegistry) Register(name, src string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.scripts[name] = redis.NewScript(src)
+}
+
+// Script returns the script with the given name.
+func (r *ScriptRegistry) Script(name string) (*redis.Script, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.scripts[name]
+	return s, ok
+}
+
+// Load loads all registered scripts onto the server.
+func (r *ScriptRegistry) Load(ctx context.Context, c redis.Scripter) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for name, s := range r.scripts {
+		if err := s.Load(ctx, c).Err(); err != nil {
+			return fmt.Errorf("redis: loading script %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (r *ScriptRegistry) run(
+	ctx context.Context,
+	c redis.Scripter,
+	name string,
+	keys []string,
+	args ...interface{},
+) *redis.Cmd {
+	s, ok := r.Script(name)
+	if !ok {
+		cmd := redis.NewCmd(ctx)
+		cmd.SetErr(fmt.Errorf("redis: unknown script %q", name))
+		return cmd
+	}
+	return s.Run(ctx, c, keys, args...)
+}
+
+// Scripts returns the script registry.
+func (c Redis) Scripts() *ScriptRegistry {
+	return c.scripts
+}
+
+// RunScript runs the registered script with the given name.
+func (c Redis) RunScript(ctx context.Context, name string, keys []string, args ...interface{}) *redis.Cmd {
+	return c.scripts.run(ctx, c.conn, name, keys, args...)
+}
+
+// LoadScripts loads all registered scripts onto the server, avoiding
+// sending the script sources on first use.
+func (c Redis) LoadScripts(ctx context.Context) error {
+	return c.scripts.Load(ctx, c.conn)
+}
+
+// GetAndDelete atomically gets the item for the given key and deletes it.
+func (c Redis) GetAndDelete(ctx context.Context, key string) cache.Item {
+	b, err := c.RunScript(ctx, getDelScript, []string{key}).Text()
+	if errors.Is(err, redis.Nil) {
+		return cache.NewItem(c.dec, []byte(nil), cache.ErrCacheMiss)
+	}
+
+	return cache.NewItem(c.dec, []byte(b), err)
+}
+
+// SetIfEqual atomically sets the item in the cache, but only if its current
+// value is equal to old. ErrNotStored is returned if the value is not equal,
+// and ErrCacheMiss if the key does not exist.
+func (c Redis) SetIfEqual(ctx context.Context, key string, old, value interface{}, expire time.Duration) error {
+	o, err := formatArg(old)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.RunScript(ctx, casScript, []string{key}, o, value, expire.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	switch res {
+	case -1:
+		return cache.ErrCacheMiss
+	case 0:
+		return cache.ErrNotStored
+	}
+	return nil
+}
+
+// DeleteIfEqual atomically deletes the item with the given key, but only if
+// its value is equal to value. ErrNotStored is returned if the value is not
+// equal, and ErrCacheMiss if the key does not exist.
+func (c Redis) DeleteIfEqual(ctx context.Context, key string, value interface{}) error {
+	v, err := formatArg(value)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.RunScript(ctx, delEqualScript, []string{key}, v).Int()
+	if err != nil {
+		return err
+	}
+
+	switch res {
+	case -1:
+		return cache.ErrCacheMiss
+	case 0:
+		return cache.ErrNotStored
+	}
+	return nil
+}
+
+// IncWithCeiling atomically increments a key by the value, unless the result
+// would exceed the ceiling. If the ceiling would be exceeded, the key is left
+// unchanged and its current value is returned with ErrNotStored.
+func (c Redis) IncWithCeiling(ctx context.Context, key string, value uint64, ceiling int64) (int64, error) {
+	res, err := c.RunScript(ctx, incrCeilingScript, []string{key}, value, ceiling).Int64Slice()
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 2 {
+		return 0, fmt.Errorf("redis: unexpected script result %v", res)
+	}
+
+	if res[1] == 0 {
+		return res[0], cache.ErrNotStored
+	}
+	return res[0], nil
+}
+
+// IncWithExpiry atomically increments a key by the value, setting the
+// expiration if the key is created by the increment.
+func (c Redis) IncWithExpiry(ctx context.Context, key string, value uint64, expire time.Duration) (int64, error) {
+	return c.RunScript(ctx, incrExpireScript, []string{key}, value, expire.Milliseconds()).Int64()
+}
+
+// IncWithOptions atomically adds the delta to the counter with the given key,
+// returning the new value. See cache.CounterCache for details.
+func (c Redis) IncWithOptions(ctx context.Context, key string, delta int64, opts cache.CounterOptions) (int64, error) {
+	if opts.Initial < 0 {
+		return 0, cache.ErrNegativeInitial
+	}
+
+	var reject int
+	if opts.Underflow == cache.UnderflowReject {
+		reject = 1
+	}
+
+	args := []interface{}{delta, opts.Initial, opts.Expire.Milliseconds(), reject}
+	res, err := c.RunScript(ctx, counterScript, []string{key}, args...).Int64Slice()
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 2 {
+		return 0, fmt.Errorf("redis: unexpected script result %v", res)
+	}
+
+	if res[1] == 0 {
+		return res[0], cache.ErrNotStored
+	}
+	return res[0], nil
+}
+
+// TakeTokens atomically refills the token bucket with the given key at rate
+// tokens per second up to burst tokens, and takes n tokens if available. It
+// returns the tokens left in the bucket, and if the tokens were taken.
+func (c Redis) TakeTokens(
+	ctx context.Context,
+	key string,
+	rate float64,
+	burst, n int64,
+	now time.Time,
+) (float64, bool, error) {
+	args := []interface{}{rate, burst, n, now.UnixMilli()}
+	res, err := c.RunScript(ctx, bucketScript, []string{key}, args...).Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(res) != 2 {
+		return 0, false, fmt.Errorf("redis: unexpected script result %v", res)
+	}
+
+	s, _ := res[0].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("redis: unexpected script result %v", res)
+	}
+	ok, _ := res[1].(int64)
+
+	return tokens, ok == 1, nil
+}
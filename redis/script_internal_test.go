@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewScriptRegistry(t *testing.T) {
+	r := NewScriptRegistry()
+
+	for name, src := range builtinScripts {
+		s, ok := r.Script(name)
+		require.True(t, ok, name)
+		assert.Equal(t, redis.NewScript(src).Hash(), s.Hash())
+	}
+}
+
+func TestScriptRegistry_Register(t *testing.T) {
+	r := NewScriptRegistry()
+
+	r.Register("test", "return 1")
+
+	s, ok := r.Script("test")
+	require.True(t, ok)
+	assert.Equal(t, "e0e1f9fabfc9d4800c877a703b823ac0578ff8db", s.Hash())
+}
+
+func TestScriptRegistry_RunUnknownScript(t *testing.T) {
+	r := NewScriptRegistry()
+
+	err := r.run(context.Background(), nil, "test", nil).Err()
+
+	assert.EqualError(t, err, `redis: unknown script "test"`)
+}
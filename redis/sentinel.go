@@ -0,0 +1,194 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const sentinelScheme = "redis+sentinel"
+
+// SentinelOptions configures the sentinels of a Redis Sentinel deployment.
+type SentinelOptions struct {
+	// MasterName is the name of the master monitored by the sentinels.
+	MasterName string
+
+	// Addrs is a seed list of sentinel addresses.
+	Addrs []string
+
+	// Username and Password are used to authenticate with the sentinels.
+	Username string
+	Password string
+
+	// ReplicaReads sends read-only operations to the replicas, falling
+	// back to the master when no replica is available.
+	ReplicaReads bool
+}
+
+// NewSentinel creates a new Redis instance using Redis Sentinel for failover.
+//
+// The options are applied to the master and replica connections.
+func NewSentinel(sentinel SentinelOptions, opts ...OptsFunc) (*Redis, error) {
+	o := &redis.Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return newSentinel(sentinel, o)
+}
+
+func newSentinelFromURL(uri string, opts ...OptsFunc) (*Redis, error) {
+	sentinel, o, err := parseSentinelURL(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return newSentinel(sentinel, o)
+}
+
+func newSentinel(sentinel SentinelOptions, o *redis.Options) (*Redis, error) {
+	if sentinel.MasterName == "" {
+		return nil, errors.New("redis: sentinel master name is required")
+	}
+	if len(sentinel.Addrs) == 0 {
+		return nil, errors.New("redis: at least one sentinel address is required")
+	}
+
+	fo := failoverOptions(sentinel, o)
+
+	// The sentinel connections are configured from the failover options,
+	// so the connect hook is only installed on the data client. Sentinels
+	// authenticate with the sentinel credentials alone.
+	var c redis.UniversalClient
+	if sentinel.ReplicaReads {
+		cc := redis.NewFailoverClusterClient(fo)
+		co := cc.Options()
+		co.OnConnect = o.OnConnect
+		// Cluster options have no database, so it is set on each node client.
+		db := o.DB
+		co.NewClient = func(opt *redis.Options) *redis.Client {
+			opt.DB = db
+			return redis.NewClient(opt)
+		}
+		// Without a routing option, read-only commands go to a random
+		// replica instead of any node including the master.
+		co.ReadOnly = true
+		c = cc
+	} else {
+		fc := redis.NewFailoverClient(fo)
+		fc.Options().OnConnect = o.OnConnect
+		c = fc
+	}
+
+	return NewWithClient(c), nil
+}
+
+// parseSentinelURL parses a sentinel URL in the form
+// redis+sentinel://[[user]:password@]host[:port][,host[:port]]/master[/db].
+//
+// The user and password are used to authenticate with the master and replicas.
+// The query parameters sentinel_username, sentinel_password and replica_reads
+// configure the sentinels.
+func parseSentinelURL(uri string) (SentinelOptions, *redis.Options, error) {
+	// The host list is not a valid URL host, so it is split out before parsing.
+	rest := strings.TrimPrefix(uri, sentinelScheme+"://")
+	if rest == uri {
+		return SentinelOptions{}, nil, errors.New("redis: invalid sentinel URL scheme")
+	}
+	hosts := rest
+	if i := strings.IndexAny(rest, "/?"); i > -1 {
+		hosts, rest = rest[:i], rest[i:]
+	} else {
+		rest = ""
+	}
+	userInfo := ""
+	if i := strings.LastIndexByte(hosts, '@'); i > -1 {
+		userInfo, hosts = hosts[:i+1], hosts[i+1:]
+	}
+
+	u, err := url.Parse(sentinelScheme + "://" + userInfo + "sentinel" + rest)
+	if err != nil {
+		return SentinelOptions{}, nil, err
+	}
+
+	o := &redis.Options{}
+	if u.User != nil {
+		o.Username = u.User.Username()
+		o.Password, _ = u.User.Password()
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) > 2 {
+		return SentinelOptions{}, nil, fmt.Errorf("redis: invalid sentinel URL path: %s", u.Path)
+	}
+	if len(parts) == 2 {
+		if o.DB, err = strconv.Atoi(parts[1]); err != nil {
+			return SentinelOptions{}, nil, fmt.Errorf("redis: invalid database number: %q", parts[1])
+		}
+	}
+
+	q := u.Query()
+	sentinel := SentinelOptions{
+		MasterName: parts[0],
+		Addrs:      sentinelAddrs(hosts),
+		Username:   q.Get("sentinel_username"),
+		Password:   q.Get("sentinel_password"),
+	}
+	if v := q.Get("replica_reads"); v != "" {
+		if sentinel.ReplicaReads, err = strconv.ParseBool(v); err != nil {
+			return SentinelOptions{}, nil, fmt.Errorf("redis: invalid replica_reads value: %q", v)
+		}
+	}
+
+	return sentinel, o, nil
+}
+
+func sentinelAddrs(hosts string) []string {
+	var addrs []string
+	for _, h := range strings.Split(hosts, ",") {
+		if h == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(h, "26379")
+		}
+		addrs = append(addrs, h)
+	}
+	return addrs
+}
+
+func failoverOptions(sentinel SentinelOptions, o *redis.Options) *redis.FailoverOptions {
+	return &redis.FailoverOptions{
+		MasterName:         sentinel.MasterName,
+		SentinelAddrs:      sentinel.Addrs,
+		SentinelUsername:   sentinel.Username,
+		SentinelPassword:   sentinel.Password,
+		Dialer:             o.Dialer,
+		Username:           o.Username,
+		Password:           o.Password,
+		DB:                 o.DB,
+		MaxRetries:         o.MaxRetries,
+		MinRetryBackoff:    o.MinRetryBackoff,
+		MaxRetryBackoff:    o.MaxRetryBackoff,
+		DialTimeout:        o.DialTimeout,
+		ReadTimeout:        o.ReadTimeout,
+		WriteTimeout:       o.WriteTimeout,
+		PoolFIFO:           o.PoolFIFO,
+		PoolSize:           o.PoolSize,
+		MinIdleConns:       o.MinIdleConns,
+		MaxConnAge:         o.MaxConnAge,
+		PoolTimeout:        o.PoolTimeout,
+		IdleTimeout:        o.IdleTimeout,
+		IdleCheckFrequency: o.IdleCheckFrequency,
+		TLSConfig:          o.TLSConfig,
+	}
+}
@@ -0,0 +1,120 @@
+package redis_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hamba/cache/v2/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSentinel_CredentialsProvider(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{
+			name: "master",
+		},
+		{
+			name:  "replica reads",
+			query: "?replica_reads=true",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			master := newStandIn(t, "user", "pass")
+			sentinel, cmds := newSentinelStandIn(t, master)
+
+			c, err := redis.New("redis+sentinel://"+sentinel+"/mymaster/2"+test.query,
+				redis.WithCredentialsProvider(func(context.Context) (string, string, error) {
+					return "user", "pass", nil
+				}),
+			)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = c.Close() })
+
+			str, err := c.Get(context.Background(), "test").String()
+			require.NoError(t, err)
+			assert.Equal(t, "foobar", str)
+			for _, cmd := range cmds() {
+				assert.NotEqual(t, "AUTH", cmd)
+				assert.NotEqual(t, "SELECT", cmd)
+			}
+		})
+	}
+}
+
+// newSentinelStandIn starts a minimal sentinel without authentication,
+// returning the master address, no replicas and rejecting unknown commands. The
+// returned function reports the commands received.
+func newSentinelStandIn(t *testing.T, master string) (string, func() []string) {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(master)
+	require.NoError(t, err)
+	addrResp := fmt.Sprintf("*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(host), host, len(port), port)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	var (
+		mu   sync.Mutex
+		cmds []string
+	)
+	serve := func(conn net.Conn) {
+		defer func() { _ = conn.Close() }()
+
+		r := bufio.NewReader(conn)
+		for {
+			args, err := readCommand(r)
+			if err != nil {
+				return
+			}
+
+			cmd := strings.ToUpper(args[0])
+			mu.Lock()
+			cmds = append(cmds, cmd)
+			mu.Unlock()
+
+			resp := "-ERR unknown command\r\n"
+			switch {
+			case cmd == "AUTH":
+				resp = "-ERR AUTH called without any password configured\r\n"
+			case cmd == "SENTINEL" && len(args) == 3 && strings.EqualFold(args[1], "get-master-addr-by-name"):
+				resp = addrResp
+			case cmd == "SENTINEL" && len(args) == 3 && strings.EqualFold(args[1], "slaves"):
+				resp = "*0\r\n"
+			}
+			if _, err = conn.Write([]byte(resp)); err != nil {
+				return
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	return ln.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), cmds...)
+	}
+}
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2"
+)
+
+// Stats returns the statistics of the servers, parsed from INFO, and of
+// the connection pool. In a cluster the masters are included, and in a
+// ring each shard. The items of all databases are counted.
+func (c Redis) Stats(ctx context.Context) (cache.Stats, error) {
+	var stats cache.Stats
+	err := c.eachNode(ctx, func(node redis.Cmdable) error {
+		info, err := node.Info(ctx).Result()
+		if err != nil {
+			return err
+		}
+		stats = stats.Add(parseInfo(info))
+		return nil
+	})
+	if err != nil {
+		return cache.Stats{}, err
+	}
+
+	ps := c.conn.PoolStats()
+	stats.Pool = cache.PoolStats{
+		Hits:       uint64(ps.Hits),
+		Misses:     uint64(ps.Misses),
+		Timeouts:   uint64(ps.Timeouts),
+		TotalConns: int(ps.TotalConns),
+		IdleConns:  int(ps.IdleConns),
+	}
+	return stats, nil
+}
+
+// parseInfo parses the statistics from an INFO response.
+func parseInfo(info string) cache.Stats {
+	var stats cache.Stats
+
+	s := bufio.NewScanner(strings.NewReader(info))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		switch field {
+		case "used_memory":
+			stats.Bytes = parseInt(value)
+		case "evicted_keys":
+			stats.Evictions = parseInt(value)
+		case "keyspace_hits":
+			stats.Hits = parseInt(value)
+		case "keyspace_misses":
+			stats.Misses = parseInt(value)
+		case "connected_clients":
+			stats.Connections = parseInt(value)
+		case "uptime_in_seconds":
+			stats.Uptime = time.Duration(parseInt(value)) * time.Second
+		default:
+			if strings.HasPrefix(field, "db") {
+				stats.Items += parseKeyspace(value)
+			}
+		}
+	}
+	return stats
+}
+
+// parseKeyspace returns the number of keys of a keyspace
+// line value, in the form keys=1,expires=0,avg_ttl=0.
+func parseKeyspace(value string) int64 {
+	for _, kv := range strings.Split(value, ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == "keys" {
+			return parseInt(v)
+		}
+	}
+	return 0
+}
+
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInfo(t *testing.T) {
+	info := "# Server\r\n" +
+		"redis_version:6.2.6\r\n" +
+		"uptime_in_seconds:3600\r\n" +
+		"\r\n" +
+		"# Clients\r\n" +
+		"connected_clients:4\r\n" +
+		"\r\n" +
+		"# Memory\r\n" +
+		"used_memory:1024\r\n" +
+		"used_memory_human:1.00K\r\n" +
+		"\r\n" +
+		"# Stats\r\n" +
+		"evicted_keys:2\r\n" +
+		"keyspace_hits:30\r\n" +
+		"keyspace_misses:10\r\n" +
+		"\r\n" +
+		"# Keyspace\r\n" +
+		"db0:keys=5,expires=1,avg_ttl=100\r\n" +
+		"db1:keys=3,expires=0,avg_ttl=0\r\n"
+
+	got := parseInfo(info)
+
+	want := cache.Stats{
+		Items:       8,
+		Bytes:       1024,
+		Evictions:   2,
+		Hits:        30,
+		Misses:      10,
+		Connections: 4,
+		Uptime:      time.Hour,
+	}
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,286 @@
+package redis_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisCache_TLSWithCredentials(t *testing.T) {
+	cert, pool := newTestCert(t)
+	addr := newTLSStandIn(t, cert, "user", "pass")
+
+	c, err := redis.New("redis://"+addr,
+		redis.WithRootCAs(pool),
+		redis.WithServerName("localhost"),
+		redis.WithMinTLSVersion(tls.VersionTLS12),
+		redis.WithCredentials("user", "pass"),
+	)
+	require.NoError(t, err)
+
+	str, err := c.Get(context.Background(), "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+}
+
+func TestRedisCache_TLSWithCredentialsProvider(t *testing.T) {
+	cert, pool := newTestCert(t)
+	addr := newTLSStandIn(t, cert, "user", "pass")
+
+	calls := 0
+	c, err := redis.New("redis://"+addr,
+		redis.WithRootCAs(pool),
+		redis.WithServerName("localhost"),
+		redis.WithCredentialsProvider(func(context.Context) (string, string, error) {
+			calls++
+			return "user", "pass", nil
+		}),
+	)
+	require.NoError(t, err)
+
+	str, err := c.Get(context.Background(), "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRedisCache_TLSWithInvalidCredentials(t *testing.T) {
+	cert, pool := newTestCert(t)
+	addr := newTLSStandIn(t, cert, "user", "pass")
+
+	c, err := redis.New("redis://"+addr,
+		redis.WithRootCAs(pool),
+		redis.WithServerName("localhost"),
+		redis.WithCredentialsProvider(func(context.Context) (string, string, error) {
+			return "user", "wrong", nil
+		}),
+	)
+	require.NoError(t, err)
+
+	err = c.Get(context.Background(), "test").Err
+	assert.Error(t, err)
+}
+
+func TestRedisCache_TLSWithUnknownCA(t *testing.T) {
+	cert, _ := newTestCert(t)
+	addr := newTLSStandIn(t, cert, "user", "pass")
+
+	c, err := redis.New("redis://"+addr,
+		redis.WithRootCAs(x509.NewCertPool()),
+		redis.WithServerName("localhost"),
+	)
+	require.NoError(t, err)
+
+	err = c.Get(context.Background(), "test").Err
+	assert.Error(t, err)
+}
+
+func TestRedisCache_TLSWithClientCert(t *testing.T) {
+	cert, pool := newTestCert(t)
+	addr := newMTLSStandIn(t, cert, pool, "user", "pass")
+
+	c, err := redis.New("redis://"+addr,
+		redis.WithRootCAs(pool),
+		redis.WithServerName("localhost"),
+		redis.WithClientCert(cert),
+		redis.WithCredentials("user", "pass"),
+	)
+	require.NoError(t, err)
+
+	str, err := c.Get(context.Background(), "test").String()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", str)
+}
+
+func TestRedisCache_TLSWithoutClientCert(t *testing.T) {
+	cert, pool := newTestCert(t)
+	addr := newMTLSStandIn(t, cert, pool, "user", "pass")
+
+	c, err := redis.New("redis://"+addr,
+		redis.WithRootCAs(pool),
+		redis.WithServerName("localhost"),
+		redis.WithCredentials("user", "pass"),
+	)
+	require.NoError(t, err)
+
+	err = c.Get(context.Background(), "test").Err
+	assert.Error(t, err)
+}
+
+func newTestCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+// newTLSStandIn starts a minimal TLS Redis server that requires ACL
+// authentication and returns "foobar" for every GET.
+func newTLSStandIn(t *testing.T, cert tls.Certificate, username, password string) string {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	require.NoError(t, err)
+
+	return serveStandIns(t, ln, username, password)
+}
+
+// newMTLSStandIn starts a TLS stand-in that also requires a client
+// certificate signed by the given pool.
+func newMTLSStandIn(t *testing.T, cert tls.Certificate, pool *x509.CertPool, username, password string) string {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})
+	require.NoError(t, err)
+
+	return serveStandIns(t, ln, username, password)
+}
+
+// newStandIn starts a plaintext stand-in.
+func newStandIn(t *testing.T, username, password string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return serveStandIns(t, ln, username, password)
+}
+
+func serveStandIns(t *testing.T, ln net.Listener, username, password string) string {
+	t.Helper()
+
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveStandIn(conn, username, password)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func serveStandIn(conn net.Conn, username, password string) {
+	defer func() { _ = conn.Close() }()
+
+	authed := false
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		var resp string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if len(args) == 3 && args[1] == username && args[2] == password {
+				authed = true
+				resp = "+OK\r\n"
+				break
+			}
+			resp = "-WRONGPASS invalid username-password pair\r\n"
+		case "SELECT":
+			if !authed {
+				resp = "-NOAUTH Authentication required.\r\n"
+				break
+			}
+			resp = "+OK\r\n"
+		case "GET":
+			if !authed {
+				resp = "-NOAUTH Authentication required.\r\n"
+				break
+			}
+			resp = "$6\r\nfoobar\r\n"
+		default:
+			resp = "-ERR unknown command\r\n"
+		}
+
+		if _, err = conn.Write([]byte(resp)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, errors.New("expected array")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid array length: %q", line)
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		l, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, l+2)
+		if _, err = io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args = append(args, string(b[:l]))
+	}
+	return args, nil
+}
@@ -0,0 +1,607 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/memory"
+)
+
+const (
+	trackingChannel = "__redis__:invalidate"
+
+	trackingHealthInterval    = 5 * time.Second
+	trackingReconnectInterval = time.Second
+)
+
+// ClientCacheOptsFunc represents an configuration function for ClientCache.
+type ClientCacheOptsFunc func(*ClientCache)
+
+// WithClientCacheSize configures the maximum number of items in the local
+// cache. The default is 10000.
+func WithClientCacheSize(n int) ClientCacheOptsFunc {
+	return func(c *ClientCache) {
+		c.size = n
+	}
+}
+
+// WithClientCacheTTL configures the expiration of items in the local cache,
+// bounding how long an item is cached without being read from Redis.
+// By default local items do not expire.
+func WithClientCacheTTL(d time.Duration) ClientCacheOptsFunc {
+	return func(c *ClientCache) {
+		c.ttl = d
+	}
+}
+
+// WithClientCacheErrorFunc configures a function called with errors
+// of the tracking connection.
+func WithClientCacheErrorFunc(fn func(error)) ClientCacheOptsFunc {
+	return func(c *ClientCache) {
+		c.errFn = fn
+	}
+}
+
+// ClientCache is a Redis adapter keeping a local cache of read items,
+// using server-assisted client-side caching to invalidate them.
+//
+// Keys read from Redis are tracked by the server, which sends an
+// invalidation to a dedicated connection when they are modified. Tracking
+// uses the RESP2 redirect mode: each read that misses the local cache is
+// pipelined with CLIENT TRACKING, redirecting invalidations to the current
+// tracking connection. When the tracking connection drops, the local cache
+// is flushed and items are not cached until it is reestablished.
+//
+// Get and GetMulti use the local cache. Writes evict the written keys from
+// the local cache before returning, so they are read back from Redis. Other
+// operations are sent to Redis. Redis 6 or later is required.
+type ClientCache struct {
+	*Redis
+
+	client   *redis.Client
+	tracking *redis.Client
+	ps       *redis.PubSub
+
+	size  int
+	ttl   time.Duration
+	errFn func(error)
+	local *memory.Memory
+
+	mu sync.Mutex
+	// connID is the client ID of the latest tracking connection.
+	connID int64
+	// id is the client ID invalidations are redirected to, or
+	// zero when the tracking connection is down.
+	id int64
+	// pending holds a token for each key being read, removed when the
+	// key is invalidated, so an invalidated read is not cached.
+	pending map[string]uint64
+	token   uint64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewClientCache returns a Redis adapter with client-side caching,
+// waiting for the tracking connection to be subscribed.
+//
+// Only single node and Sentinel failover clients are supported.
+func NewClientCache(ctx context.Context, r *Redis, opts ...ClientCacheOptsFunc) (*ClientCache, error) {
+	client, ok := r.conn.(*redis.Client)
+	if !ok {
+		return nil, errors.New("redis: client-side caching requires a single node client")
+	}
+
+	c := &ClientCache{
+		Redis:   r,
+		client:  client,
+		size:    10000,
+		errFn:   func(error) {},
+		pending: map[string]uint64{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.local = memory.New(memory.WithMaxEntries(c.size))
+
+	c.tracking = redis.NewClient(c.trackingOptions(client.Options()))
+	c.ps = c.tracking.Subscribe(ctx, trackingChannel)
+	if err := c.receiveSubscription(ctx); err != nil {
+		_ = c.ps.Close()
+		_ = c.tracking.Close()
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go c.run(runCtx)
+
+	return c, nil
+}
+
+// trackingOptions returns the options of the tracking connection,
+// recording its client ID each time it connects.
+func (c *ClientCache) trackingOptions(opts *redis.Options) *redis.Options {
+	o := *opts
+	o.PoolSize = 1
+	o.MinIdleConns = 0
+
+	onConnect := o.OnConnect
+	o.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if onConnect != nil {
+			if err := onConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+
+		id, err := cn.ClientID(ctx).Result()
+		if err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		c.connID = id
+		c.mu.Unlock()
+		return nil
+	}
+	return &o
+}
+
+// receiveSubscription waits for the tracking subscription to be confirmed.
+func (c *ClientCache) receiveSubscription(ctx context.Context) error {
+	for {
+		msg, err := c.ps.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := msg.(*redis.Subscription); ok {
+			c.subscribed()
+			return nil
+		}
+	}
+}
+
+// Get gets the item for the given key.
+func (c *ClientCache) Get(ctx context.Context, key string) cache.Item {
+	if item := c.local.Get(ctx, key); item.Err == nil {
+		return item
+	}
+
+	return c.getTracked(ctx, []string{key})[0]
+}
+
+// GetMulti gets the items for the given keys.
+func (c *ClientCache) GetMulti(ctx context.Context, keys ...string) ([]cache.Item, error) {
+	items, _ := c.local.GetMulti(ctx, keys...)
+
+	var (
+		missed []string
+		idxs   []int
+	)
+	for i, item := range items {
+		if item.Err != nil {
+			missed = append(missed, keys[i])
+			idxs = append(idxs, i)
+		}
+	}
+	if len(missed) == 0 {
+		return items, nil
+	}
+
+	for j, item := range c.getTracked(ctx, missed) {
+		items[idxs[j]] = item
+	}
+	return items, nil
+}
+
+// getTracked reads the keys from Redis, caching them locally if
+// they are tracked.
+func (c *ClientCache) getTracked(ctx context.Context, keys []string) []cache.Item {
+	id, tokens := c.reserve(keys)
+	defer c.release(keys, tokens)
+
+	var track *redis.Cmd
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, _ = c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		if id != 0 {
+			track = p.Do(ctx, "CLIENT", "TRACKING", "on", "REDIRECT", id)
+		}
+		for i, k := range keys {
+			cmds[i] = p.Get(ctx, k)
+		}
+		return nil
+	})
+	tracked := track != nil && track.Err() == nil
+
+	items := make([]cache.Item, len(keys))
+	for i, cmd := range cmds {
+		b, err := cmd.Bytes()
+		switch {
+		case errors.Is(err, redis.Nil):
+			err = cache.ErrCacheMiss
+		case err == nil && tracked:
+			c.store(ctx, keys[i], tokens[i], b)
+		}
+		items[i] = cache.NewItem(c.dec, b, err)
+	}
+	return items
+}
+
+// reserve returns the tracking client ID and a pending token for each key,
+// or a zero ID if the tracking connection is down.
+func (c *ClientCache) reserve(keys []string) (int64, []uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.id == 0 {
+		return 0, nil
+	}
+
+	tokens := make([]uint64, len(keys))
+	for i, k := range keys {
+		c.token++
+		tokens[i] = c.token
+		c.pending[k] = c.token
+	}
+	return c.id, tokens
+}
+
+// store caches the value of the key, unless it was invalidated
+// since the key was reserved.
+func (c *ClientCache) store(ctx context.Context, key string, token uint64, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending[key] != token {
+		return
+	}
+	delete(c.pending, key)
+	_ = c.local.Set(ctx, key, b, c.ttl)
+}
+
+func (c *ClientCache) release(keys []string, tokens []uint64) {
+	if len(tokens) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, k := range keys {
+		if c.pending[k] == tokens[i] {
+			delete(c.pending, k)
+		}
+	}
+}
+
+// Set sets the item in the cache.
+func (c *ClientCache) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.Set(ctx, key, value, expire)
+}
+
+// Add sets the item in the cache, but only if the key does not already exist.
+func (c *ClientCache) Add(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.Add(ctx, key, value, expire)
+}
+
+// Replace sets the item in the cache, but only if the key already exists.
+func (c *ClientCache) Replace(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.Replace(ctx, key, value, expire)
+}
+
+// Delete deletes the item with the given key.
+func (c *ClientCache) Delete(ctx context.Context, key string) error {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.Delete(ctx, key)
+}
+
+// Inc increments a key by the value.
+func (c *ClientCache) Inc(ctx context.Context, key string, value uint64) (int64, error) {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.Inc(ctx, key, value)
+}
+
+// Dec decrements a key by the value.
+func (c *ClientCache) Dec(ctx context.Context, key string, value uint64) (int64, error) {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.Dec(ctx, key, value)
+}
+
+// SetMulti sets the entries in the cache in a single pipeline.
+func (c *ClientCache) SetMulti(ctx context.Context, entries ...cache.Entry) []error {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	defer c.invalidate(ctx, keys...)
+
+	return c.Redis.SetMulti(ctx, entries...)
+}
+
+// DeleteMulti deletes the items with the given keys in a single pipeline.
+func (c *ClientCache) DeleteMulti(ctx context.Context, keys ...string) []error {
+	defer c.invalidate(ctx, keys...)
+
+	return c.Redis.DeleteMulti(ctx, keys...)
+}
+
+// ExecBatch executes the operations in a single pipeline, returning
+// an item for each operation in order.
+func (c *ClientCache) ExecBatch(ctx context.Context, ops []cache.BatchOp) []cache.Item {
+	var keys []string
+	for _, op := range ops {
+		if op.Type != cache.BatchGet {
+			keys = append(keys, op.Key)
+		}
+	}
+	defer c.invalidate(ctx, keys...)
+
+	return c.Redis.ExecBatch(ctx, ops)
+}
+
+// IncFloat increments a key by the floating-point value.
+func (c *ClientCache) IncFloat(ctx context.Context, key string, value float64) (float64, error) {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.IncFloat(ctx, key, value)
+}
+
+// CompareAndSwap sets the item in the cache, but only if it has not been
+// modified since the token was read.
+func (c *ClientCache) CompareAndSwap(
+	ctx context.Context,
+	key string,
+	value interface{},
+	token cache.CASToken,
+	expire time.Duration,
+) error {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.CompareAndSwap(ctx, key, value, token, expire)
+}
+
+// RunScript runs the registered script with the given name.
+func (c *ClientCache) RunScript(ctx context.Context, name string, keys []string, args ...interface{}) *redis.Cmd {
+	defer c.invalidate(ctx, keys...)
+
+	return c.Redis.RunScript(ctx, name, keys, args...)
+}
+
+// GetAndDelete atomically gets the item for the given key and deletes it.
+func (c *ClientCache) GetAndDelete(ctx context.Context, key string) cache.Item {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.GetAndDelete(ctx, key)
+}
+
+// SetIfEqual atomically sets the item in the cache, but only if its current
+// value is equal to old.
+func (c *ClientCache) SetIfEqual(ctx context.Context, key string, old, value interface{}, expire time.Duration) error {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.SetIfEqual(ctx, key, old, value, expire)
+}
+
+// DeleteIfEqual atomically deletes the item with the given key, but only if
+// its value is equal to value.
+func (c *ClientCache) DeleteIfEqual(ctx context.Context, key string, value interface{}) error {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.DeleteIfEqual(ctx, key, value)
+}
+
+// IncWithCeiling atomically increments a key by the value, unless the result
+// would exceed the ceiling.
+func (c *ClientCache) IncWithCeiling(ctx context.Context, key string, value uint64, ceiling int64) (int64, error) {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.IncWithCeiling(ctx, key, value, ceiling)
+}
+
+// IncWithExpiry atomically increments a key by the value, setting the
+// expiration if the key is created by the increment.
+func (c *ClientCache) IncWithExpiry(
+	ctx context.Context,
+	key string,
+	value uint64,
+	expire time.Duration,
+) (int64, error) {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.IncWithExpiry(ctx, key, value, expire)
+}
+
+// IncWithOptions atomically adds the delta to the counter with the given key,
+// returning the new value.
+func (c *ClientCache) IncWithOptions(
+	ctx context.Context,
+	key string,
+	delta int64,
+	opts cache.CounterOptions,
+) (int64, error) {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.IncWithOptions(ctx, key, delta, opts)
+}
+
+// TakeTokens atomically refills the token bucket with the given key and
+// takes n tokens if available.
+func (c *ClientCache) TakeTokens(
+	ctx context.Context,
+	key string,
+	rate float64,
+	burst, n int64,
+	now time.Time,
+) (float64, bool, error) {
+	defer c.invalidate(ctx, key)
+
+	return c.Redis.TakeTokens(ctx, key, rate, burst, n, now)
+}
+
+// Tx executes fn in an optimistic transaction, watching the given keys.
+func (c *ClientCache) Tx(ctx context.Context, keys []string, fn func(tx *Tx) error) error {
+	var written []string
+	defer func() { c.invalidate(ctx, written...) }()
+
+	return c.Redis.Tx(ctx, keys, func(tx *Tx) error {
+		err := fn(tx)
+		for k := range tx.vals {
+			written = append(written, k)
+		}
+		return err
+	})
+}
+
+// DeletePattern deletes the keys matching the pattern, returning the
+// number of deleted keys.
+func (c *ClientCache) DeletePattern(ctx context.Context, pattern string, opts DeletePatternOptions) (int64, error) {
+	if !opts.DryRun {
+		defer c.invalidatePattern(ctx, pattern)
+	}
+
+	return c.Redis.DeletePattern(ctx, pattern, opts)
+}
+
+// invalidatePattern evicts the keys matching the pattern from the local cache.
+func (c *ClientCache) invalidatePattern(ctx context.Context, pattern string) {
+	var keys []string
+	_ = c.local.Scan(ctx, pattern, cache.ScanOptions{}, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	c.invalidate(ctx, keys...)
+}
+
+// invalidate evicts the keys from the local cache.
+func (c *ClientCache) invalidate(ctx context.Context, keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, k := range keys {
+		delete(c.pending, k)
+		_ = c.local.Delete(ctx, k)
+	}
+}
+
+// flush evicts all items from the local cache. If the tracking
+// connection is down, items are not cached until it is resubscribed.
+func (c *ClientCache) flush(ctx context.Context, down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if down {
+		c.id = 0
+	}
+	c.pending = map[string]uint64{}
+	_ = c.local.Flush(ctx)
+}
+
+// subscribed redirects invalidations to the latest tracking connection.
+func (c *ClientCache) subscribed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.id = c.connID
+}
+
+// Flush removes all keys from the database and the local cache.
+func (c *ClientCache) Flush(ctx context.Context) error {
+	defer c.flush(ctx, false)
+
+	return c.Redis.Flush(ctx)
+}
+
+// Close stops tracking and closes the client, releasing its connections.
+func (c *ClientCache) Close() error {
+	c.cancel()
+	err := c.ps.Close()
+	c.wg.Wait()
+
+	for _, fn := range []func() error{c.tracking.Close, c.Redis.Close} {
+		if cerr := fn(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// run receives invalidations, flushing the local cache when the
+// tracking connection drops.
+func (c *ClientCache) run(ctx context.Context) {
+	defer c.wg.Done()
+
+	for {
+		err := c.receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		c.flush(ctx, true)
+		c.errFn(err)
+
+		t := time.NewTimer(trackingReconnectInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// receive handles tracking messages until the connection fails. The
+// subscription is restored by the next receive.
+func (c *ClientCache) receive(ctx context.Context) error {
+	for {
+		msg, err := c.ps.ReceiveTimeout(ctx, trackingHealthInterval)
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				return err
+			}
+			if err = c.ps.Ping(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" {
+				c.subscribed()
+			}
+		case *redis.Message:
+			if m.Channel != trackingChannel {
+				continue
+			}
+			c.handleInvalidation(ctx, m)
+		}
+	}
+}
+
+func (c *ClientCache) handleInvalidation(ctx context.Context, m *redis.Message) {
+	switch {
+	case len(m.PayloadSlice) > 0:
+		c.invalidate(ctx, m.PayloadSlice...)
+	case m.Payload != "":
+		c.invalidate(ctx, m.Payload)
+	default:
+		// A null payload is sent when the database is flushed.
+		c.flush(ctx, false)
+	}
+}
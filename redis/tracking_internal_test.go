@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2"
+	"github.com/hamba/cache/v2/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientCache_RequiresSingleNodeClient(t *testing.T) {
+	r := NewWithClient(redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"localhost:7000"}}))
+
+	_, err := NewClientCache(context.Background(), r)
+
+	assert.Error(t, err)
+}
+
+func TestClientCache_StoreSkipsInvalidatedKeys(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClientCache()
+
+	id, tokens := c.reserve([]string{"a", "b"})
+	require.Equal(t, int64(1), id)
+
+	c.handleInvalidation(ctx, &redis.Message{Channel: trackingChannel, PayloadSlice: []string{"a"}})
+	c.store(ctx, "a", tokens[0], []byte("1"))
+	c.store(ctx, "b", tokens[1], []byte("2"))
+
+	assert.Error(t, c.local.Get(ctx, "a").Err)
+	str, err := c.local.Get(ctx, "b").String()
+	require.NoError(t, err)
+	assert.Equal(t, "2", str)
+	assert.Empty(t, c.pending)
+}
+
+func TestClientCache_FlushOnNullInvalidation(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClientCache()
+	_ = c.local.Set(ctx, "a", "1", 0)
+
+	c.handleInvalidation(ctx, &redis.Message{Channel: trackingChannel})
+
+	assert.Equal(t, 0, c.local.Len())
+	assert.Equal(t, int64(1), c.id)
+}
+
+func TestClientCache_NotCachedWhileDown(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClientCache()
+
+	_, tokens := c.reserve([]string{"a"})
+	c.flush(ctx, true)
+	c.store(ctx, "a", tokens[0], []byte("1"))
+
+	assert.Equal(t, 0, c.local.Len())
+	id, tokens := c.reserve([]string{"a"})
+	assert.Equal(t, int64(0), id)
+	assert.Nil(t, tokens)
+
+	c.connID = 2
+	c.subscribed()
+
+	id, _ = c.reserve([]string{"a"})
+	assert.Equal(t, int64(2), id)
+}
+
+func TestClientCache_WritesEvictLocalKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(ctx context.Context, c *ClientCache)
+	}{
+		{
+			name: "set multi",
+			fn: func(ctx context.Context, c *ClientCache) {
+				_ = c.SetMulti(ctx, cache.Entry{Key: "test", Value: "foo"})
+			},
+		},
+		{
+			name: "delete multi",
+			fn: func(ctx context.Context, c *ClientCache) {
+				_ = c.DeleteMulti(ctx, "test")
+			},
+		},
+		{
+			name: "exec batch",
+			fn: func(ctx context.Context, c *ClientCache) {
+				_ = c.ExecBatch(ctx, []cache.BatchOp{{Type: cache.BatchInc, Key: "test", Delta: 1}})
+			},
+		},
+		{
+			name: "inc float",
+			fn: func(ctx context.Context, c *ClientCache) {
+				_, _ = c.IncFloat(ctx, "test", 1)
+			},
+		},
+		{
+			name: "compare and swap",
+			fn: func(ctx context.Context, c *ClientCache) {
+				_ = c.CompareAndSwap(ctx, "test", "foo", casToken("1"), 0)
+			},
+		},
+		{
+			name: "get and delete",
+			fn: func(ctx context.Context, c *ClientCache) {
+				_ = c.GetAndDelete(ctx, "test")
+			},
+		},
+		{
+			name: "inc with options",
+			fn: func(ctx context.Context, c *ClientCache) {
+				_, _ = c.IncWithOptions(ctx, "test", 1, cache.CounterOptions{})
+			},
+		},
+		{
+			name: "delete pattern",
+			fn: func(ctx context.Context, c *ClientCache) {
+				_, _ = c.DeletePattern(ctx, "te*", DeletePatternOptions{})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := newTestClientCache()
+			c.Redis = NewWithClient(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}))
+			_ = c.local.Set(ctx, "test", "1", 0)
+			_ = c.local.Set(ctx, "other", "1", 0)
+
+			test.fn(ctx, c)
+
+			assert.ErrorIs(t, c.local.Get(ctx, "test").Err, cache.ErrCacheMiss)
+			assert.NoError(t, c.local.Get(ctx, "other").Err)
+		})
+	}
+}
+
+func newTestClientCache() *ClientCache {
+	return &ClientCache{
+		local:   memory.New(),
+		connID:  1,
+		id:      1,
+		pending: map[string]uint64{},
+	}
+}
@@ -0,0 +1,220 @@
+package redis
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hamba/cache/v2"
+)
+
+// txMaxAttempts is the maximum number of attempts of a transaction.
+const txMaxAttempts = 10
+
+// Tx is an optimistic transaction on a set of watched keys.
+//
+// Reads are executed immediately, while writes are queued and executed
+// atomically when the transaction function returns. Reads see the writes
+// queued earlier in the transaction, and the results of writes are computed
+// from the watched values, so they are only consistent for watched keys.
+type Tx struct {
+	tx   *redis.Tx
+	pipe redis.Pipeliner
+	dec  cache.Decoder
+
+	// vals holds the values of the keys written in the transaction,
+	// with nil for deleted keys.
+	vals map[string][]byte
+}
+
+// Tx executes fn in an optimistic transaction, watching the given keys.
+//
+// If a watched key is modified before the transaction is committed, the
+// transaction is retried. An error wrapping cache.ErrCASConflict is returned
+// if the transaction could not be committed. When using a cluster or ring,
+// all keys must be on the same node.
+func (c Redis) Tx(ctx context.Context, keys []string, fn func(tx *Tx) error) error {
+	for i := 0; i < txMaxAttempts; i++ {
+		err := c.conn.Watch(ctx, func(rtx *redis.Tx) error {
+			tx := &Tx{
+				tx:   rtx,
+				pipe: rtx.TxPipeline(),
+				dec:  c.dec,
+				vals: map[string][]byte{},
+			}
+			if err := fn(tx); err != nil {
+				return err
+			}
+
+			_, err := tx.pipe.Exec(ctx)
+			return err
+		}, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("redis: transaction failed after %d attempts: %w", txMaxAttempts, cache.ErrCASConflict)
+}
+
+// Get gets the item for the given key.
+func (t *Tx) Get(ctx context.Context, key string) cache.Item {
+	b, ok, err := t.get(ctx, key)
+	if err == nil && !ok {
+		err = cache.ErrCacheMiss
+	}
+
+	return cache.NewItem(t.dec, b, err)
+}
+
+// GetMulti gets the items for the given keys.
+func (t *Tx) GetMulti(ctx context.Context, keys ...string) ([]cache.Item, error) {
+	items := make([]cache.Item, 0, len(keys))
+	for _, k := range keys {
+		b, ok, err := t.get(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			items = append(items, cache.NewItem(t.dec, []byte(nil), cache.ErrCacheMiss))
+			continue
+		}
+		items = append(items, cache.NewItem(t.dec, b, nil))
+	}
+
+	return items, nil
+}
+
+// Set queues setting the item in the cache.
+func (t *Tx) Set(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	b, err := formatArg(value)
+	if err != nil {
+		return err
+	}
+
+	t.pipe.Set(ctx, key, b, expire)
+	t.vals[key] = b
+	return nil
+}
+
+// Add queues setting the item in the cache, but only if the key does not already exist.
+func (t *Tx) Add(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	_, ok, err := t.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return cache.ErrNotStored
+	}
+
+	return t.Set(ctx, key, value, expire)
+}
+
+// Replace queues setting the item in the cache, but only if the key already exists.
+func (t *Tx) Replace(ctx context.Context, key string, value interface{}, expire time.Duration) error {
+	_, ok, err := t.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return cache.ErrNotStored
+	}
+
+	return t.Set(ctx, key, value, expire)
+}
+
+// Delete queues deleting the item with the given key.
+func (t *Tx) Delete(ctx context.Context, key string) error {
+	t.pipe.Del(ctx, key)
+	t.vals[key] = nil
+	return nil
+}
+
+// Inc queues incrementing a key by the value, returning the value
+// the key will have once the transaction is committed.
+func (t *Tx) Inc(ctx context.Context, key string, value uint64) (int64, error) {
+	return t.incrBy(ctx, key, int64(value))
+}
+
+// Dec queues decrementing a key by the value, returning the value
+// the key will have once the transaction is committed.
+func (t *Tx) Dec(ctx context.Context, key string, value uint64) (int64, error) {
+	return t.incrBy(ctx, key, -int64(value))
+}
+
+func (t *Tx) incrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	b, ok, err := t.get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	if ok {
+		n, err = strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return 0, errors.New("redis: value is not an integer")
+		}
+	}
+	n += delta
+
+	t.pipe.IncrBy(ctx, key, delta)
+	t.vals[key] = []byte(strconv.FormatInt(n, 10))
+	return n, nil
+}
+
+// get gets the value of the key, including the writes queued in the transaction.
+func (t *Tx) get(ctx context.Context, key string) ([]byte, bool, error) {
+	if b, ok := t.vals[key]; ok {
+		return b, b != nil, nil
+	}
+
+	b, err := t.tx.Get(ctx, key).Bytes()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return nil, false, nil
+	case err != nil:
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// formatArg formats a value as it is sent to the server.
+func formatArg(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case int, int8, int16, int32, int64:
+		return []byte(fmt.Sprintf("%d", v)), nil
+	case uint, uint8, uint16, uint32, uint64:
+		return []byte(fmt.Sprintf("%d", v)), nil
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'f', -1, 64), nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case time.Time:
+		return v.AppendFormat(nil, time.RFC3339Nano), nil
+	case time.Duration:
+		return strconv.AppendInt(nil, v.Nanoseconds(), 10), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	default:
+		return nil, fmt.Errorf("redis: can't marshal %T (implement encoding.BinaryMarshaler)", v)
+	}
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTx_ImplementsCache(t *testing.T) {
+	assert.Implements(t, (*cache.Cache)(nil), &Tx{})
+}
+
+func TestFormatArg(t *testing.T) {
+	tests := []struct {
+		name string
+		val  interface{}
+		want string
+	}{
+		{name: "nil", val: nil, want: ""},
+		{name: "string", val: "foo", want: "foo"},
+		{name: "bytes", val: []byte("foo"), want: "foo"},
+		{name: "int", val: -1, want: "-1"},
+		{name: "uint", val: uint8(2), want: "2"},
+		{name: "float32", val: float32(1.5), want: "1.5"},
+		{name: "float64", val: 1.25, want: "1.25"},
+		{name: "true", val: true, want: "1"},
+		{name: "false", val: false, want: "0"},
+		{name: "time", val: time.Date(2022, 1, 2, 3, 4, 5, 6, time.UTC), want: "2022-01-02T03:04:05.000000006Z"},
+		{name: "duration", val: time.Second, want: "1000000000"},
+		{name: "binary marshaler", val: &url.URL{Scheme: "http", Host: "test"}, want: "http://test"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			got, err := formatArg(test.val)
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, string(got))
+		})
+	}
+}
+
+func TestFormatArg_Unsupported(t *testing.T) {
+	_, err := formatArg(struct{}{})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// ScanOptions configures a scan.
+type ScanOptions struct {
+	// BatchSize is the number of keys requested from the server at a time.
+	// It is a hint, and defaults to 100.
+	BatchSize int
+
+	// Delay is the pause between batches, limiting the load on the server.
+	Delay time.Duration
+}
+
+// Scanner represents a cache instance that can enumerate its keys.
+type Scanner interface {
+	Cache
+
+	// Scan calls fn with each key matching the glob-style pattern, as
+	// supported by Redis. The scan stops at the first error returned by fn.
+	//
+	// Keys added or removed during the scan may or may not be passed to
+	// fn, and a key may be passed more than once.
+	Scan(ctx context.Context, pattern string, opts ScanOptions, fn func(key string) error) error
+}
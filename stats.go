@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Stats are the statistics of a cache. The server statistics are
+// summed across the servers of the cache.
+type Stats struct {
+	// Items is the number of items stored.
+	Items int64
+
+	// Bytes is the memory used by the servers.
+	Bytes int64
+
+	// Evictions is the number of items evicted to free memory.
+	Evictions int64
+
+	// Hits and Misses are the number of key lookups that found
+	// an item, and that did not.
+	Hits   int64
+	Misses int64
+
+	// Connections is the number of open server connections.
+	Connections int64
+
+	// Uptime is the shortest time any server has been running,
+	// covering the period of the counters of all servers.
+	Uptime time.Duration
+
+	// Pool are the statistics of the client connection pool.
+	Pool PoolStats
+}
+
+// HitRatio returns the ratio of lookups that found an item.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Add returns the sum of the statistics, with the shortest
+// non-zero uptime.
+func (s Stats) Add(o Stats) Stats {
+	uptime := s.Uptime
+	if uptime == 0 || (o.Uptime > 0 && o.Uptime < uptime) {
+		uptime = o.Uptime
+	}
+
+	return Stats{
+		Items:       s.Items + o.Items,
+		Bytes:       s.Bytes + o.Bytes,
+		Evictions:   s.Evictions + o.Evictions,
+		Hits:        s.Hits + o.Hits,
+		Misses:      s.Misses + o.Misses,
+		Connections: s.Connections + o.Connections,
+		Uptime:      uptime,
+		Pool: PoolStats{
+			Hits:       s.Pool.Hits + o.Pool.Hits,
+			Misses:     s.Pool.Misses + o.Pool.Misses,
+			Timeouts:   s.Pool.Timeouts + o.Pool.Timeouts,
+			TotalConns: s.Pool.TotalConns + o.Pool.TotalConns,
+			IdleConns:  s.Pool.IdleConns + o.Pool.IdleConns,
+		},
+	}
+}
+
+// PoolStats are the statistics of a client connection pool.
+type PoolStats struct {
+	// Hits is the number of times an idle connection was reused.
+	Hits uint64
+
+	// Misses is the number of times a new connection was needed.
+	Misses uint64
+
+	// Timeouts is the number of times waiting for a connection timed out.
+	Timeouts uint64
+
+	// TotalConns is the number of open connections.
+	TotalConns int
+
+	// IdleConns is the number of idle connections.
+	IdleConns int
+}
+
+// StatsCache represents a cache instance that can report its statistics.
+type StatsCache interface {
+	Cache
+
+	// Stats returns the statistics of the cache.
+	Stats(ctx context.Context) (Stats, error)
+}
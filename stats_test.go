@@ -0,0 +1,44 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hamba/cache/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats_HitRatio(t *testing.T) {
+	assert.Equal(t, 0.0, cache.Stats{}.HitRatio())
+	assert.Equal(t, 0.75, cache.Stats{Hits: 3, Misses: 1}.HitRatio())
+}
+
+func TestStats_Add(t *testing.T) {
+	a := cache.Stats{
+		Items:       1,
+		Bytes:       2,
+		Evictions:   3,
+		Hits:        4,
+		Misses:      5,
+		Connections: 6,
+		Uptime:      time.Hour,
+		Pool:        cache.PoolStats{Hits: 1, Misses: 2, Timeouts: 3, TotalConns: 4, IdleConns: 5},
+	}
+	b := a
+	b.Uptime = time.Minute
+
+	got := a.Add(b)
+
+	want := cache.Stats{
+		Items:       2,
+		Bytes:       4,
+		Evictions:   6,
+		Hits:        8,
+		Misses:      10,
+		Connections: 12,
+		Uptime:      time.Minute,
+		Pool:        cache.PoolStats{Hits: 2, Misses: 4, Timeouts: 6, TotalConns: 8, IdleConns: 10},
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, time.Hour, cache.Stats{}.Add(cache.Stats{Uptime: time.Hour}).Uptime)
+}